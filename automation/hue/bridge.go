@@ -4,6 +4,7 @@ package hue
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -331,8 +332,15 @@ func (bridge Bridge) Subscribe(
 	return
 }
 
+// Send a command to the given bridge, returning its response.
 func (bridge Bridge) Send(method, uri string, payload any) (response Response, err error) {
 
+	return bridge.SendContext(context.Background(), method, uri, payload)
+}
+
+// Like Send, but the request is canceled when the given context is done.
+func (bridge Bridge) SendContext(ctx context.Context, method, uri string, payload any) (response Response, err error) {
+
 	url := fmt.Sprintf(`https://%s/clip/v2/%s`, bridge.address, uri)
 
 	var body io.Reader
@@ -354,7 +362,7 @@ func (bridge Bridge) Send(method, uri string, payload any) (response Response, e
 
 	var req *http.Request
 
-	if req, err = http.NewRequest(method, url, body); err != nil {
+	if req, err = http.NewRequestWithContext(ctx, method, url, body); err != nil {
 		return
 	}
 
@@ -419,3 +427,12 @@ func walkRawMessage(items chan<- Item, errors chan<- error, datum any) {
 		errors <- fmt.Errorf("unsupported SSE payload %v of type %T", v, v)
 	}
 }
+
+// Return the value of the "id" field of the resource described by the given
+// item, or "" if it has none. Use as the key for utilities.Coalesce to keep
+// only the latest update to each resource.
+func (item Item) Id() string {
+
+	id, _ := item["id"].(string)
+	return id
+}
@@ -3,6 +3,7 @@
 package powerview
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -68,10 +69,21 @@ func NewHub(label, address string) Hub {
 // Send a command to the given PowerView hub to activate the given scene.
 func (hub Hub) Activate(scene Scene) (err error) {
 
+	return hub.ActivateContext(context.Background(), scene)
+}
+
+// Like Activate, but the request is canceled when the given context is done.
+func (hub Hub) ActivateContext(ctx context.Context, scene Scene) (err error) {
+
 	url := fmt.Sprintf(`http://%s/scenes?sceneId=%d`, hub.address, scene.Id)
 
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody); err != nil {
+		return
+	}
+
 	var resp *http.Response
-	if resp, err = http.DefaultClient.Get(url); err != nil {
+	if resp, err = http.DefaultClient.Do(req); err != nil {
 		return
 	}
 
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -12,9 +13,27 @@ import (
 	"parasaurolophus/automation/trigger"
 	"parasaurolophus/utilities"
 	"strconv"
+	"strings"
 	"time"
 )
 
+type (
+
+	// An item received from the hue bridge at the given index.
+	hueEvent struct {
+		bridge int
+		item   hue.Item
+	}
+
+	// A file to which hue items are written, as they are received, as the
+	// elements of a JSON array.
+	eventLog struct {
+		file    *os.File
+		encoder *json.Encoder
+		count   int
+	}
+)
+
 func main() {
 
 	///////////////////////////////////////////////////////////////////////////
@@ -98,69 +117,59 @@ func main() {
 	writeOuputJSON("basement_hue_model.json", basementModel)
 
 	///////////////////////////////////////////////////////////////////////////
-	// subscribe to SSE messages from both hue briges and invoke the
-	// synchronous API on each
-
-	groundFloorItems, groundFloorErrors, groundFloorTerminate, groundFloorAwait, err :=
-		groundFloorBridge.Subscribe(onHueConnect, onHueDisconnect)
+	// subscribe to SSE messages from all of the hue briges under a supervisor,
+	// so that a dropped connection to one of them is re-established rather
+	// than ending the program, and merge the items they send into a single
+	// channel
+
+	bridges := []hue.Bridge{groundFloorBridge, basementBridge}
+	hueSubscriptions := make([]utilities.ChildSpec, len(bridges))
+	hueEventChannels := make([]<-chan hueEvent, len(bridges))
+	hueEventsTerminate := make(chan any)
+
+	for i, bridge := range bridges {
+		items := make(chan hue.Item)
+		hueSubscriptions[i] = utilities.ChildSpec{Name: bridge.Label, Start: subscribe(bridge, items)}
+		hueEventChannels[i], _ = utilities.Map(hueEventsTerminate, items, func(item hue.Item) hueEvent {
+			return hueEvent{bridge: i, item: item}
+		})
+	}
 
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		return
+	hueSupervisorOptions := utilities.SupervisorOptions{
+		MaxRestarts: 10,
+		Period:      time.Hour,
+		MinBackoff:  time.Second,
+		MaxBackoff:  time.Minute,
 	}
 
-	defer utilities.CloseAndWait(groundFloorTerminate, groundFloorAwait)
+	hueSupervisor, hueSupervisorTerminate, hueSupervisorAwait :=
+		utilities.StartSupervisor(hueSupervisorOptions, hueSubscriptions)
 
-	basementItems, basementErrors, basementTerminate, basementAwait, err :=
-		basementBridge.Subscribe(onHueConnect, onHueDisconnect)
+	defer utilities.CloseAndWait(hueSupervisorTerminate, hueSupervisorAwait)
 
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		return
-	}
+	hueEvents, hueEventsAwait := utilities.Merge(hueEventsTerminate, hueEventChannels...)
 
-	defer utilities.CloseAndWait(basementTerminate, basementAwait)
+	defer utilities.CloseAndWait(hueEventsTerminate, hueEventsAwait)
 
 	///////////////////////////////////////////////////////////////////////////
 	// handle the asynchronous events from all of the above
 
-	var (
-		groundFloorEventsFile    *os.File
-		groundFloorEventCount    = 0
-		groundFloorEventsEncoder *json.Encoder
-		basementEventsFile       *os.File
-		basementEventCount       = 0
-		basementEventsEncoder    *json.Encoder
-		triggerEventsFile        *os.File
-	)
+	hueEventLogs := make([]*eventLog, len(bridges))
 
-	groundFloorEventsFile, err = os.Create("ground_floor_hue_events.json")
+	for i, bridge := range bridges {
 
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		return
-	}
-
-	defer groundFloorEventsFile.Close()
-	defer fmt.Fprintln(groundFloorEventsFile, "]")
+		filename := strings.ToLower(strings.ReplaceAll(bridge.Label, " ", "_")) + "_hue_events.json"
+		hueEventLogs[i], err = createEventLog(filename)
 
-	fmt.Fprintln(groundFloorEventsFile, "[")
-	groundFloorEventsEncoder = json.NewEncoder(groundFloorEventsFile)
-	groundFloorEventsEncoder.SetIndent("  ", "  ")
-	basementEventsFile, err = os.Create("basement_hue_events.json")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return
+		}
 
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		return
+		defer hueEventLogs[i].Close()
 	}
 
-	defer basementEventsFile.Close()
-	defer fmt.Fprintln(basementEventsFile, "]")
-
-	fmt.Fprintln(basementEventsFile, "[")
-	basementEventsEncoder = json.NewEncoder(basementEventsFile)
-	basementEventsEncoder.SetIndent("  ", "  ")
-	triggerEventsFile, err = os.Create("trigger_events.txt")
+	triggerEventsFile, err := os.Create("trigger_events.txt")
 
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -174,36 +183,14 @@ HandleEvents:
 
 		select {
 
-		case groundFloorEvent := <-groundFloorItems:
-			if groundFloorEventCount > 0 {
-				fmt.Fprintln(groundFloorEventsFile, ",")
-			}
-			groundFloorEventCount++
-			err = groundFloorEventsEncoder.Encode(groundFloorEvent)
-			if err != nil {
-				break HandleEvents
-			}
-
-		case err = <-groundFloorErrors:
-			break HandleEvents
-
-		case <-groundFloorAwait:
-			break HandleEvents
-
-		case basementEvent := <-basementItems:
-			if basementEventCount > 0 {
-				fmt.Fprintln(basementEventsFile, ",")
-			}
-			basementEventCount++
-			err = basementEventsEncoder.Encode(basementEvent)
+		case event := <-hueEvents:
+			err = hueEventLogs[event.bridge].write(event.item)
 			if err != nil {
 				break HandleEvents
 			}
 
-		case err = <-basementErrors:
-			break HandleEvents
-
-		case <-basementAwait:
+		case <-hueSupervisorAwait:
+			err = hueSupervisor.Err()
 			break HandleEvents
 
 		case event := <-triggers:
@@ -294,6 +281,59 @@ func getEnvVars() (
 	return
 }
 
+// Return a function for use as a supervised child which subscribes to SSE
+// messages from the given bridge, forwarding them to the given channel. It
+// returns an error, so that the supervisor will restart it, if the
+// subscription fails or reports an error.
+func subscribe(bridge hue.Bridge, items chan<- hue.Item) func(context.Context) error {
+
+	return func(ctx context.Context) error {
+
+		bridgeItems, bridgeErrors, bridgeTerminate, bridgeAwait, err :=
+			bridge.Subscribe(onHueConnect, onHueDisconnect)
+
+		if err != nil {
+			return err
+		}
+
+		// discard anything the subscription sends while shutting down, lest
+		// it block forever
+		defer func() {
+			close(bridgeTerminate)
+			for {
+				select {
+				case <-bridgeItems:
+				case <-bridgeErrors:
+				case <-bridgeAwait:
+					return
+				}
+			}
+		}()
+
+		for {
+
+			select {
+
+			case item := <-bridgeItems:
+				select {
+				case items <- item:
+				case <-ctx.Done():
+					return nil
+				}
+
+			case err = <-bridgeErrors:
+				return err
+
+			case <-bridgeAwait:
+				return fmt.Errorf("%s subscription ended", bridge.Label)
+
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
 func onHueConnect(bridge hue.Bridge) {
 
 	fmt.Printf("hue hub at %s connected @ %s\n", bridge.Label, time.Now().Format(time.RFC850))
@@ -305,6 +345,40 @@ func onHueDisconnect(bridge hue.Bridge) {
 	fmt.Fprintln(os.Stderr, err.Error())
 }
 
+// Create the named file and write the start of a JSON array to it.
+func createEventLog(filename string) (*eventLog, error) {
+
+	file, err := os.Create(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(file, "[")
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("  ", "  ")
+
+	return &eventLog{file: file, encoder: encoder}, nil
+}
+
+// Write the given item as the next element of the JSON array.
+func (log *eventLog) write(item hue.Item) error {
+
+	if log.count > 0 {
+		fmt.Fprintln(log.file, ",")
+	}
+
+	log.count++
+	return log.encoder.Encode(item)
+}
+
+// Write the end of the JSON array and close the file.
+func (log *eventLog) Close() error {
+
+	fmt.Fprintln(log.file, "]")
+	return log.file.Close()
+}
+
 func writeOuputJSON(filename string, object any) {
 
 	file, err := os.Create(filename)
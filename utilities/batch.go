@@ -0,0 +1,315 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+type (
+
+	// How ProcessBatchWithErrors distributes the values sent by its generate
+	// function among its transformers.
+	//
+	// See ProcessBatchWithErrors
+	DispatchMode int
+
+	// How ProcessBatchWithErrors responds to errors returned by its generate,
+	// transform and consume functions.
+	//
+	// See ProcessBatchWithErrors
+	ErrorMode int
+
+	// Error returned by ProcessBatchWithErrors for a particular item. Index is
+	// the zero-based position of the item in the sequence of values sent by
+	// the batch's generate function.
+	//
+	// See ProcessBatchWithErrors
+	ItemError struct {
+		Index int
+		Err   error
+	}
+)
+
+const (
+
+	// Pass generate one channel per transformer, to which it should send
+	// values in round-robin fashion.
+	RoundRobin = DispatchMode(iota)
+
+	// Pass generate a single channel from which all of the transformers
+	// receive values as soon as they are idle.
+	SharedQueue
+)
+
+const (
+
+	// Cancel the batch on the first error and return it.
+	FailFast = ErrorMode(iota)
+
+	// Process every item and return all of the errors, joined.
+	CollectAll
+)
+
+func (err *ItemError) Error() string {
+
+	return fmt.Sprintf("item %d: %s", err.Index, err.Err.Error())
+}
+
+func (err *ItemError) Unwrap() error {
+
+	return err.Err
+}
+
+type (
+
+	// A value tagged with its position in the sequence of values sent by a
+	// batch's generate function. A skipped value is a placeholder for an item
+	// that failed, so that the ones after it need not wait for it forever.
+	indexed[V any] struct {
+		index int
+		value V
+		skip  bool
+	}
+
+	// Reassembles values into index order, allowing at most size of them to
+	// be pending at any one time.
+	reorderBuffer[V any] struct {
+		lock    sync.Mutex
+		ready   *sync.Cond
+		next    int
+		size    int
+		pending map[int]indexed[V]
+	}
+
+	// State shared by the goroutines started by ProcessBatchWithErrors.
+	batch struct {
+		ctx    context.Context
+		cancel context.CancelFunc
+		mode   ErrorMode
+		lock   sync.Mutex
+		errs   []error
+	}
+)
+
+// Return a batch whose context is derived from the given one.
+func newBatch(ctx context.Context, mode ErrorMode) *batch {
+
+	b := &batch{mode: mode}
+	b.ctx, b.cancel = context.WithCancel(ctx)
+	return b
+}
+
+// Record the given error, canceling the batch in FailFast mode. Errors
+// reported after the batch's context is done are ignored since they are most
+// likely the result of the cancellation itself.
+func (b *batch) fail(err error) {
+
+	defer b.lock.Unlock()
+	b.lock.Lock()
+
+	if b.ctx.Err() != nil {
+		return
+	}
+
+	b.errs = append(b.errs, err)
+
+	if b.mode == FailFast {
+		b.cancel()
+	}
+}
+
+// Return the error, if any, with which the batch completed.
+func (b *batch) err(parent context.Context) error {
+
+	defer b.lock.Unlock()
+	b.lock.Lock()
+
+	if len(b.errs) == 0 {
+		return parent.Err()
+	}
+
+	if b.mode == FailFast {
+		return b.errs[0]
+	}
+
+	// report item errors in index order, followed by any others
+	slices.SortStableFunc(b.errs, func(x, y error) int {
+		var ix, iy *ItemError
+		switch {
+		case errors.As(x, &ix) && errors.As(y, &iy):
+			return ix.Index - iy.Index
+		case ix != nil:
+			return -1
+		case errors.As(y, &iy):
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return errors.Join(append(b.errs, parent.Err())...)
+}
+
+// Return a reorderBuffer of the given size. Any goroutines blocked in its wait
+// method are released when ctx is done.
+func newReorderBuffer[V any](ctx context.Context, size int) *reorderBuffer[V] {
+
+	r := &reorderBuffer[V]{
+		size:    max(size, 1),
+		pending: map[int]indexed[V]{},
+	}
+	r.ready = sync.NewCond(&r.lock)
+	context.AfterFunc(ctx, func() {
+		defer r.lock.Unlock()
+		r.lock.Lock()
+		r.ready.Broadcast()
+	})
+	return r
+}
+
+// Block until the item with the given index may be processed without causing
+// more than size items to be pending, or until ctx is done.
+func (r *reorderBuffer[V]) wait(ctx context.Context, index int) {
+
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	for index >= r.next+r.size && ctx.Err() == nil {
+		r.ready.Wait()
+	}
+}
+
+// Add the given item to the buffer then pass every item that is no longer
+// waiting on an earlier one to emit, in index order.
+func (r *reorderBuffer[V]) add(item indexed[V], emit func(indexed[V])) {
+
+	r.lock.Lock()
+	r.pending[item.index] = item
+	r.lock.Unlock()
+
+	for {
+		r.lock.Lock()
+		item, ok := r.pending[r.next]
+		if ok {
+			delete(r.pending, r.next)
+			r.next++
+			r.ready.Broadcast()
+		}
+		r.lock.Unlock()
+		if !ok {
+			return
+		}
+		emit(item)
+	}
+}
+
+// Return the function used to route values to transformers according to
+// options.Key, or nil if values are not routed by key.
+func keyRoute[V any](options BatchOptions, numTransformers int) func(V) int {
+
+	if options.Key == nil || options.Dispatch == SharedQueue {
+		return nil
+	}
+
+	partitioner := NewPartitioner(numTransformers, func(value V) string {
+		return options.Key(value)
+	})
+
+	return partitioner.Partition
+}
+
+// Return one channel for each of the given outputs and start a goroutine that
+// receives values from them in the order they are sent, forwarding each to the
+// output with the same position tagged with its place in that order. The
+// returned channels are unbuffered so as to preserve the order in which they
+// are sent by a single goroutine. The await channel will be closed once all of
+// the returned channels have been closed and all of the values sent to them
+// have been forwarded. If gate is not nil, it is invoked with each value's
+// index before that value is forwarded. If route is not nil, a single channel
+// is returned instead and each value sent to it is forwarded to the output
+// whose position route returns for it.
+//
+// Receiving from more than one channel in send order requires reflect.Select,
+// which costs considerably more per value than a plain receive, so a single
+// channel is received from directly.
+func sequence[V any](
+
+	outputs []chan<- indexed[V],
+	gate func(int),
+	route func(V) int,
+
+) (
+
+	inputs []chan<- V,
+	await <-chan any,
+
+) {
+
+	n := len(outputs)
+	if route != nil {
+		n = 1
+	}
+	channels := make([]chan V, n)
+	inputs = make([]chan<- V, n)
+
+	for i := range n {
+		channels[i] = make(chan V)
+		inputs[i] = channels[i]
+	}
+
+	a := make(chan any)
+	await = a
+	index := 0
+
+	// tag the given value with its index and send it to the given output,
+	// or the one chosen by route
+	tag := func(i int, value V) {
+		if gate != nil {
+			gate(index)
+		}
+		if route != nil {
+			i = route(value)
+		}
+		outputs[i] <- indexed[V]{index: index, value: value}
+		index++
+	}
+
+	if n == 1 {
+		go func() {
+			defer close(a)
+			for value := range channels[0] {
+				tag(0, value)
+			}
+		}()
+		return
+	}
+
+	cases := make([]reflect.SelectCase, n)
+
+	for i, c := range channels {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c)}
+	}
+
+	go func() {
+		defer close(a)
+		for open := n; open > 0; {
+			i, v, ok := reflect.Select(cases)
+			if !ok {
+				// a zero Chan causes reflect.Select to ignore the case
+				cases[i].Chan = reflect.Value{}
+				open--
+				continue
+			}
+			value, _ := v.Interface().(V)
+			tag(i, value)
+		}
+	}()
+
+	return
+}
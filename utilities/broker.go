@@ -0,0 +1,316 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+
+	// What a Broker does when a subscriber's buffer is full.
+	//
+	// See SubscriptionOptions
+	SlowSubscriberPolicy int
+
+	// Parameters for a Broker subscription.
+	//
+	// See Broker
+	SubscriptionOptions struct {
+
+		// Size of the subscription's channel buffer.
+		BufferSize int
+
+		// What to do when the subscription's buffer is full.
+		Policy SlowSubscriberPolicy
+	}
+
+	// Delivers each value published to it to every current subscription
+	// whose topics or predicate accept it, so that any number of consumers
+	// can see the same stream of values. For example, to share the items
+	// received from a hue bridge:
+	//
+	//	items, errors, bridgeTerminate, bridgeAwait, err := bridge.Subscribe(onConnect, onDisconnect)
+	//	...
+	//	broker := NewBroker(func(item hue.Item) string {
+	//	  t, _ := item["type"].(string)
+	//	  return t
+	//	})
+	//	defer broker.Close()
+	//	terminate := make(chan any)
+	//	await := broker.PublishFrom(terminate, items)
+	//	defer CloseAndWait(terminate, await)
+	//	lights := broker.SubscribeTopics(SubscriptionOptions{BufferSize: 10}, "light")
+	//	defer lights.Unsubscribe()
+	//	for item := range lights.Values() {
+	//	  ...
+	//	}
+	//
+	// A Broker's methods may be invoked concurrently. Values published by a
+	// given goroutine are delivered to each subscription in the order in
+	// which they were published.
+	//
+	// See NewBroker, Subscription, SubscriptionOptions
+	Broker[T any] struct {
+		topic         func(T) string
+		lock          sync.Mutex
+		subscriptions []*Subscription[T]
+		closed        bool
+	}
+
+	// A subscription to the values published to a Broker.
+	//
+	// See Broker
+	Subscription[T any] struct {
+		broker       *Broker[T]
+		accept       func(T) bool
+		policy       SlowSubscriberPolicy
+		values       chan T
+		done         chan any
+		once         sync.Once
+		lock         sync.RWMutex
+		dropped      atomic.Int64
+		disconnected atomic.Bool
+	}
+)
+
+const (
+
+	// Discard values published while the subscription's buffer is full.
+	DropMessages = SlowSubscriberPolicy(iota)
+
+	// Make the publisher wait until there is room in the subscription's
+	// buffer, slowing delivery to every other subscription as well.
+	BlockPublisher
+
+	// Unsubscribe the subscription if a value is published while its buffer
+	// is full.
+	Disconnect
+)
+
+// Return a new Broker which uses the given function to determine the topic of
+// each value published to it. Topic may be nil if only predicate subscriptions
+// will be used.
+//
+// See Broker
+func NewBroker[T any](topic func(T) string) *Broker[T] {
+
+	if topic == nil {
+		topic = func(T) string { return "" }
+	}
+
+	return &Broker[T]{topic: topic}
+}
+
+// Return a new subscription to the values published to the broker for which
+// the given predicate returns true, or to all of them if predicate is nil. The
+// predicate is invoked by the publishing goroutine. If the broker has been
+// closed, the subscription's channel is already closed.
+//
+// See SubscribeTopics, Subscription
+func (broker *Broker[T]) Subscribe(options SubscriptionOptions, predicate func(T) bool) *Subscription[T] {
+
+	if predicate == nil {
+		predicate = func(T) bool { return true }
+	}
+
+	subscription := &Subscription[T]{
+		broker: broker,
+		accept: predicate,
+		policy: options.Policy,
+		values: make(chan T, options.BufferSize),
+		done:   make(chan any),
+	}
+
+	if !broker.add(subscription) {
+		subscription.Unsubscribe()
+	}
+
+	return subscription
+}
+
+// Return a new subscription to the values published to the broker whose topic
+// is one of the given ones.
+//
+// See Subscribe, Subscription
+func (broker *Broker[T]) SubscribeTopics(options SubscriptionOptions, topics ...string) *Subscription[T] {
+
+	return broker.Subscribe(options, func(value T) bool {
+		return slices.Contains(topics, broker.topic(value))
+	})
+}
+
+// Deliver the given value to each subscription which accepts it, according to
+// each one's SlowSubscriberPolicy. Does nothing once the broker is closed.
+//
+// See PublishFrom
+func (broker *Broker[T]) Publish(value T) {
+
+	for _, subscription := range broker.snapshot() {
+		if subscription.accept(value) {
+			subscription.deliver(value)
+		}
+	}
+}
+
+// Publish each value received from input until input or terminate is closed.
+// The returned await channel is closed when the publishing goroutine exits.
+//
+// See Publish
+func (broker *Broker[T]) PublishFrom(terminate <-chan any, input <-chan T) (await <-chan any) {
+
+	a := make(chan any)
+	await = a
+
+	go func() {
+		defer close(a)
+		for {
+			select {
+			case value, ok := <-input:
+				if !ok {
+					return
+				}
+				broker.Publish(value)
+			case <-terminate:
+				return
+			}
+		}
+	}()
+
+	return
+}
+
+// Unsubscribe all of the broker's subscriptions and stop accepting new ones.
+//
+// See Subscription.Unsubscribe
+func (broker *Broker[T]) Close() {
+
+	subscriptions := func() []*Subscription[T] {
+		defer broker.lock.Unlock()
+		broker.lock.Lock()
+		broker.closed = true
+		return slices.Clone(broker.subscriptions)
+	}()
+
+	for _, subscription := range subscriptions {
+		subscription.Unsubscribe()
+	}
+}
+
+// Return the channel to which the subscription's values are sent. It is
+// closed when the subscription ends.
+func (subscription *Subscription[T]) Values() <-chan T {
+
+	return subscription.values
+}
+
+// End the subscription, closing its channel once any publisher currently
+// sending to it has given up. Values already buffered may still be received.
+// Safe to call more than once.
+func (subscription *Subscription[T]) Unsubscribe() {
+
+	subscription.once.Do(func() {
+		close(subscription.done)
+		subscription.broker.remove(subscription)
+		defer subscription.lock.Unlock()
+		subscription.lock.Lock()
+		close(subscription.values)
+	})
+}
+
+// Return the number of values discarded because the subscription's buffer was
+// full, under the DropMessages policy.
+func (subscription *Subscription[T]) Dropped() int {
+
+	return int(subscription.dropped.Load())
+}
+
+// Return true if the subscription was ended, under the Disconnect policy,
+// because its buffer was full.
+func (subscription *Subscription[T]) Disconnected() bool {
+
+	return subscription.disconnected.Load()
+}
+
+func (broker *Broker[T]) add(subscription *Subscription[T]) bool {
+
+	defer broker.lock.Unlock()
+	broker.lock.Lock()
+
+	if broker.closed {
+		return false
+	}
+
+	broker.subscriptions = append(broker.subscriptions, subscription)
+	return true
+}
+
+func (broker *Broker[T]) remove(subscription *Subscription[T]) {
+
+	defer broker.lock.Unlock()
+	broker.lock.Lock()
+
+	broker.subscriptions = slices.DeleteFunc(broker.subscriptions, func(s *Subscription[T]) bool {
+		return s == subscription
+	})
+}
+
+// Return the current subscriptions, so that values can be delivered to them
+// without holding the broker's lock.
+func (broker *Broker[T]) snapshot() []*Subscription[T] {
+
+	defer broker.lock.Unlock()
+	broker.lock.Lock()
+
+	if broker.closed {
+		return nil
+	}
+
+	return slices.Clone(broker.subscriptions)
+}
+
+func (subscription *Subscription[T]) deliver(value T) {
+
+	if subscription.send(value) {
+		subscription.disconnected.Store(true)
+		subscription.Unsubscribe()
+	}
+}
+
+// Send the given value according to the subscription's policy, unless it has
+// ended. Returns true if the subscription should be disconnected.
+func (subscription *Subscription[T]) send(value T) (disconnect bool) {
+
+	// holding the read lock prevents Unsubscribe from closing the values
+	// channel while sending to it
+	defer subscription.lock.RUnlock()
+	subscription.lock.RLock()
+
+	select {
+	case <-subscription.done:
+		return
+	default:
+	}
+
+	if subscription.policy == BlockPublisher {
+		select {
+		case subscription.values <- value:
+		case <-subscription.done:
+		}
+		return
+	}
+
+	select {
+	case subscription.values <- value:
+	default:
+		if subscription.policy == Disconnect {
+			disconnect = true
+		} else {
+			subscription.dropped.Add(1)
+		}
+	}
+
+	return
+}
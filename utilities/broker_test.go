@@ -0,0 +1,101 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"parasaurolophus/utilities"
+	"slices"
+	"testing"
+	"time"
+)
+
+type event struct {
+	topic string
+	value int
+}
+
+func newEventBroker() *utilities.Broker[event] {
+	return utilities.NewBroker(func(e event) string { return e.topic })
+}
+
+func TestBrokerTopics(t *testing.T) {
+	broker := newEventBroker()
+	defer broker.Close()
+	options := utilities.SubscriptionOptions{BufferSize: 10}
+	lights := broker.SubscribeTopics(options, "light")
+	all := broker.Subscribe(options, nil)
+	odd := broker.Subscribe(options, func(e event) bool { return e.value%2 == 1 })
+	for i, topic := range []string{"light", "scene", "light", "motion"} {
+		broker.Publish(event{topic, i})
+	}
+	broker.Close()
+	if actual := collect(lights.Values()); !slices.Equal(actual, []event{{"light", 0}, {"light", 2}}) {
+		t.Errorf("unexpected lights %v", actual)
+	}
+	if actual := collect(all.Values()); len(actual) != 4 {
+		t.Errorf("unexpected all %v", actual)
+	}
+	if actual := collect(odd.Values()); !slices.Equal(actual, []event{{"scene", 1}, {"motion", 3}}) {
+		t.Errorf("unexpected odd %v", actual)
+	}
+}
+
+func TestBrokerSlowSubscribers(t *testing.T) {
+	broker := newEventBroker()
+	defer broker.Close()
+	drop := broker.Subscribe(utilities.SubscriptionOptions{BufferSize: 2, Policy: utilities.DropMessages}, nil)
+	disconnect := broker.Subscribe(utilities.SubscriptionOptions{BufferSize: 2, Policy: utilities.Disconnect}, nil)
+	block := broker.Subscribe(utilities.SubscriptionOptions{BufferSize: 0, Policy: utilities.BlockPublisher}, nil)
+	received := make(chan []event)
+	go func() {
+		received <- collect(block.Values())
+	}()
+	for i := range 5 {
+		broker.Publish(event{"light", i})
+	}
+	if drop.Dropped() != 3 || drop.Disconnected() {
+		t.Errorf("expected 3 dropped, got %d", drop.Dropped())
+	}
+	if !disconnect.Disconnected() {
+		t.Error("expected disconnect")
+	}
+	if actual := collect(disconnect.Values()); len(actual) != 2 {
+		t.Errorf("expected 2 buffered values, got %v", actual)
+	}
+	block.Unsubscribe()
+	if actual := <-received; len(actual) != 5 {
+		t.Errorf("expected 5 values, got %v", actual)
+	}
+}
+
+func TestBrokerUnsubscribeWhileBlocked(t *testing.T) {
+	broker := newEventBroker()
+	defer broker.Close()
+	subscription := broker.Subscribe(utilities.SubscriptionOptions{Policy: utilities.BlockPublisher}, nil)
+	published := make(chan any)
+	go func() {
+		defer close(published)
+		broker.Publish(event{"light", 1})
+	}()
+	time.Sleep(time.Millisecond * 5)
+	subscription.Unsubscribe()
+	subscription.Unsubscribe()
+	<-published
+	broker.Publish(event{"light", 2})
+	if actual := collect(subscription.Values()); len(actual) != 0 {
+		t.Errorf("expected nothing, got %v", actual)
+	}
+}
+
+func TestBrokerPublishFrom(t *testing.T) {
+	broker := utilities.NewBroker[int](nil)
+	subscription := broker.Subscribe(utilities.SubscriptionOptions{BufferSize: 3}, nil)
+	<-broker.PublishFrom(make(chan any), send(1, 2, 3))
+	broker.Close()
+	if actual := collect(subscription.Values()); !slices.Equal(actual, []int{1, 2, 3}) {
+		t.Errorf("unexpected %v", actual)
+	}
+	if s := broker.Subscribe(utilities.SubscriptionOptions{}, nil); len(collect(s.Values())) != 0 {
+		t.Error("expected closed subscription")
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"time"
+)
+
+// Group the values received from input into slices of at most maxSize values
+// and send each slice to the returned output channel once it is full or its
+// first value has waited for maxLatency, whichever comes first, until input or
+// terminate is closed. This allows a consumer to write or send values in bulk.
+// For example:
+//
+//	terminate := make(chan any)
+//	chunks, await := Chunk(terminate, rows, 100, time.Second)
+//	defer CloseAndWait(terminate, await)
+//	for chunk := range chunks {
+//	  if err := insertRows(chunk); err != nil {
+//	    ...
+//	  }
+//	}
+//
+// A partial slice is sent before the output channel is closed if input is
+// closed, but not if terminate is. A maxLatency of zero or less means that
+// slices are sent only when full or when input is closed, while a maxSize of
+// zero or less means that slices are sent only after maxLatency or when input
+// is closed, however many values they contain. Chunk panics if neither
+// maxSize nor maxLatency is positive, since slices would then only be sent
+// when input is closed. Each slice sent is
+// newly allocated, so receivers may retain them. The returned output channel
+// is closed, followed by the returned await channel, when the forwarding
+// goroutine exits.
+//
+// See Coalesce, Debounce, OrDone
+func Chunk[V any](
+
+	terminate <-chan any,
+	input <-chan V,
+	maxSize int,
+	maxLatency time.Duration,
+
+) (
+
+	output <-chan []V,
+	await <-chan any,
+
+) {
+
+	if maxSize <= 0 && maxLatency <= 0 {
+		panic("Chunk: at least one of maxSize and maxLatency must be positive")
+	}
+
+	o := make(chan []V)
+	a := make(chan any)
+	output = o
+	await = a
+
+	go func() {
+
+		defer close(a)
+		defer close(o)
+
+		var (
+			chunk []V
+			timer <-chan time.Time
+		)
+
+		flush := func() bool {
+			timer = nil
+			if len(chunk) == 0 {
+				return true
+			}
+			c := chunk
+			chunk = nil
+			return sendOrDone(terminate, o, c)
+		}
+
+		for {
+
+			select {
+
+			case value, ok := <-input:
+				if !ok {
+					flush()
+					return
+				}
+				if chunk == nil {
+					chunk = make([]V, 0, max(maxSize, 0))
+					if maxLatency > 0 {
+						timer = time.After(maxLatency)
+					}
+				}
+				chunk = append(chunk, value)
+				if maxSize > 0 && len(chunk) >= maxSize && !flush() {
+					return
+				}
+
+			case <-timer:
+				if !flush() {
+					return
+				}
+
+			case <-terminate:
+				return
+			}
+		}
+	}()
+
+	return
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"parasaurolophus/utilities"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestChunkBySize(t *testing.T) {
+	terminate := make(chan any)
+	chunks, await := utilities.Chunk(terminate, send(1, 2, 3, 4, 5, 6, 7), 3, time.Hour)
+	defer utilities.CloseAndWait(terminate, await)
+	actual := collect(chunks)
+	expected := [][]int{{1, 2, 3}, {4, 5, 6}, {7}}
+	if !slices.EqualFunc(actual, expected, slices.Equal) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestChunkByLatency(t *testing.T) {
+	input := make(chan int)
+	terminate := make(chan any)
+	chunks, await := utilities.Chunk(terminate, input, 100, time.Millisecond*20)
+	defer utilities.CloseAndWait(terminate, await)
+	go func() {
+		defer close(input)
+		input <- 1
+		input <- 2
+		time.Sleep(time.Millisecond * 50)
+		input <- 3
+	}()
+	actual := collect(chunks)
+	expected := [][]int{{1, 2}, {3}}
+	if !slices.EqualFunc(actual, expected, slices.Equal) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestChunkTerminate(t *testing.T) {
+	input := make(chan int)
+	terminate := make(chan any)
+	chunks, await := utilities.Chunk(terminate, input, 10, 0)
+	input <- 1
+	close(terminate)
+	<-await
+	if actual := collect(chunks); len(actual) != 0 {
+		t.Errorf("expected nothing, got %v", actual)
+	}
+}
+
+func TestChunkNoSizeLimit(t *testing.T) {
+	for _, maxSize := range []int{0, -1} {
+		input := make(chan int)
+		terminate := make(chan any)
+		chunks, await := utilities.Chunk(terminate, input, maxSize, time.Millisecond*20)
+		go func() {
+			defer close(input)
+			for i := range 10 {
+				input <- i
+			}
+			time.Sleep(time.Millisecond * 50)
+			input <- 10
+		}()
+		actual := collect(chunks)
+		utilities.CloseAndWait(terminate, await)
+		expected := [][]int{{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, {10}}
+		if !slices.EqualFunc(actual, expected, slices.Equal) {
+			t.Errorf("maxSize %d: expected %v, got %v", maxSize, expected, actual)
+		}
+	}
+}
+
+func TestChunkNoLimits(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	utilities.Chunk(nil, send(1), 0, 0)
+}
@@ -0,0 +1,329 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Forward each value received from input to the returned output channel until
+// either input or terminate is closed. Use OrDone to range over a channel
+// whose sender may never close it without blocking forever. The returned
+// output channel is closed, followed by the returned await channel, when the
+// forwarding goroutine exits. For example:
+//
+//	terminate := make(chan any)
+//	values, await := OrDone(terminate, events)
+//	defer CloseAndWait(terminate, await)
+//	for value := range values {
+//	  ...
+//	}
+//
+// Closing terminate does not close or drain input, so a goroutine blocked
+// sending to it remains blocked.
+//
+// See FanOut, Filter, Map, Merge, Tee
+func OrDone[V any](
+
+	terminate <-chan any,
+	input <-chan V,
+
+) (
+
+	output <-chan V,
+	await <-chan any,
+
+) {
+
+	return Filter(terminate, input, func(V) bool { return true })
+}
+
+// Forward each value received from any of the given inputs to the returned
+// output channel, in the order in which they are received, until all of the
+// inputs or terminate are closed. The returned output channel is closed,
+// followed by the returned await channel, once every forwarding goroutine has
+// exited. For example:
+//
+//	terminate := make(chan any)
+//	items, await := Merge(terminate, groundFloorItems, basementItems)
+//	defer CloseAndWait(terminate, await)
+//	for item := range items {
+//	  ...
+//	}
+//
+// See OrDone, Tee
+func Merge[V any](
+
+	terminate <-chan any,
+	inputs ...<-chan V,
+
+) (
+
+	output <-chan V,
+	await <-chan any,
+
+) {
+
+	o := make(chan V)
+	a := make(chan any)
+	output = o
+	await = a
+	group := sync.WaitGroup{}
+	group.Add(len(inputs))
+
+	for _, input := range inputs {
+		go func() {
+			defer group.Done()
+			forward(terminate, input, o, func(V) bool { return true })
+		}()
+	}
+
+	go func() {
+		defer close(a)
+		defer close(o)
+		group.Wait()
+	}()
+
+	return
+}
+
+// Send each value received from input to every one of the n returned outputs
+// until input or terminate is closed. Each value is sent to the outputs in
+// whatever order they are ready to receive it, but the next value is not
+// received from input until every output has received the current one, so the
+// slowest receiver sets the pace for all of them. The returned outputs are
+// closed, followed by the returned await channel, when the forwarding
+// goroutine exits.
+//
+// See FanOut, Merge, OrDone
+func Tee[V any](
+
+	terminate <-chan any,
+	input <-chan V,
+	n int,
+
+) (
+
+	outputs []<-chan V,
+	await <-chan any,
+
+) {
+
+	o, outputs := makeChannels[V](n)
+	a := make(chan any)
+	await = a
+
+	go func() {
+		defer close(a)
+		defer closeChannels(o)
+		for {
+			select {
+			case value, ok := <-input:
+				if !ok {
+					return
+				}
+				cases := sendCases(terminate, o, value)
+				for remaining := n; remaining > 0; remaining-- {
+					i, _, _ := reflect.Select(cases)
+					if i == n {
+						return
+					}
+					// a zero Chan causes reflect.Select to ignore the case
+					cases[i].Chan = reflect.Value{}
+				}
+			case <-terminate:
+				return
+			}
+		}
+	}()
+
+	return
+}
+
+// Send each value received from input to whichever one of the n returned
+// outputs is first ready to receive it, until input or terminate is closed.
+// The returned outputs are closed, followed by the returned await channel,
+// when the forwarding goroutine exits.
+//
+// See Merge, StartSharedWorkers, Tee
+func FanOut[V any](
+
+	terminate <-chan any,
+	input <-chan V,
+	n int,
+
+) (
+
+	outputs []<-chan V,
+	await <-chan any,
+
+) {
+
+	o, outputs := makeChannels[V](n)
+	a := make(chan any)
+	await = a
+
+	go func() {
+		defer close(a)
+		defer closeChannels(o)
+		for {
+			select {
+			case value, ok := <-input:
+				if !ok {
+					return
+				}
+				if i, _, _ := reflect.Select(sendCases(terminate, o, value)); i == n {
+					return
+				}
+			case <-terminate:
+				return
+			}
+		}
+	}()
+
+	return
+}
+
+// Send the result of applying the given function to each value received from
+// input to the returned output channel until input or terminate is closed. The
+// returned output channel is closed, followed by the returned await channel,
+// when the forwarding goroutine exits.
+//
+// See Filter, OrDone
+func Map[In any, Out any](
+
+	terminate <-chan any,
+	input <-chan In,
+	fn func(In) Out,
+
+) (
+
+	output <-chan Out,
+	await <-chan any,
+
+) {
+
+	o := make(chan Out)
+	a := make(chan any)
+	output = o
+	await = a
+
+	go func() {
+		defer close(a)
+		defer close(o)
+		for {
+			select {
+			case value, ok := <-input:
+				if !ok {
+					return
+				}
+				select {
+				case o <- fn(value):
+				case <-terminate:
+					return
+				}
+			case <-terminate:
+				return
+			}
+		}
+	}()
+
+	return
+}
+
+// Forward the values received from input for which the given predicate
+// returns true to the returned output channel until input or terminate is
+// closed. The returned output channel is closed, followed by the returned
+// await channel, when the forwarding goroutine exits.
+//
+// See Map, OrDone
+func Filter[V any](
+
+	terminate <-chan any,
+	input <-chan V,
+	predicate func(V) bool,
+
+) (
+
+	output <-chan V,
+	await <-chan any,
+
+) {
+
+	o := make(chan V)
+	a := make(chan any)
+	output = o
+	await = a
+
+	go func() {
+		defer close(a)
+		defer close(o)
+		forward(terminate, input, o, predicate)
+	}()
+
+	return
+}
+
+// Send the values received from input for which predicate returns true to
+// output until input or terminate is closed.
+func forward[V any](terminate <-chan any, input <-chan V, output chan<- V, predicate func(V) bool) {
+
+	for {
+		select {
+		case value, ok := <-input:
+			if !ok {
+				return
+			}
+			if !predicate(value) {
+				continue
+			}
+			select {
+			case output <- value:
+			case <-terminate:
+				return
+			}
+		case <-terminate:
+			return
+		}
+	}
+}
+
+// Return n new channels along with receive-only views of them.
+func makeChannels[V any](n int) (channels []chan V, outputs []<-chan V) {
+
+	channels = make([]chan V, n)
+	outputs = make([]<-chan V, n)
+
+	for i := range n {
+		channels[i] = make(chan V)
+		outputs[i] = channels[i]
+	}
+
+	return
+}
+
+// Close each of the given channels.
+func closeChannels[V any](channels []chan V) {
+
+	for _, c := range channels {
+		close(c)
+	}
+}
+
+// Return select cases which send the given value to each of the given
+// channels, followed by one which receives from terminate.
+func sendCases[V any](terminate <-chan any, channels []chan V, value V) []reflect.SelectCase {
+
+	// use a pointer so that a nil interface value is still a valid Send
+	v := reflect.ValueOf(&value).Elem()
+	cases := make([]reflect.SelectCase, len(channels)+1)
+
+	for i, c := range channels {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(c), Send: v}
+	}
+
+	cases[len(channels)] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(terminate)}
+
+	return cases
+}
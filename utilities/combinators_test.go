@@ -0,0 +1,117 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"parasaurolophus/utilities"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func send(values ...int) <-chan int {
+	c := make(chan int)
+	go func() {
+		defer close(c)
+		for _, v := range values {
+			c <- v
+		}
+	}()
+	return c
+}
+
+func TestMerge(t *testing.T) {
+	terminate := make(chan any)
+	output, await := utilities.Merge(terminate, send(1, 2, 3), send(4, 5), send())
+	defer utilities.CloseAndWait(terminate, await)
+	actual := []int{}
+	for v := range output {
+		actual = append(actual, v)
+	}
+	slices.Sort(actual)
+	if !slices.Equal(actual, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("unexpected %v", actual)
+	}
+}
+
+func TestMergeTerminate(t *testing.T) {
+	terminate := make(chan any)
+	blocked := make(chan int)
+	output, await := utilities.Merge(terminate, blocked)
+	close(terminate)
+	<-await
+	if _, ok := <-output; ok {
+		t.Error("expected output to be closed")
+	}
+}
+
+func TestTee(t *testing.T) {
+	terminate := make(chan any)
+	outputs, await := utilities.Tee(terminate, send(1, 2, 3), 3)
+	defer utilities.CloseAndWait(terminate, await)
+	results := make([][]int, len(outputs))
+	group := sync.WaitGroup{}
+	group.Add(len(outputs))
+	for i, output := range outputs {
+		go func() {
+			defer group.Done()
+			for v := range output {
+				results[i] = append(results[i], v)
+			}
+		}()
+	}
+	group.Wait()
+	for i, r := range results {
+		if !slices.Equal(r, []int{1, 2, 3}) {
+			t.Errorf("output %d: unexpected %v", i, r)
+		}
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	terminate := make(chan any)
+	outputs, await := utilities.FanOut(terminate, send(1, 2, 3, 4, 5, 6), 2)
+	defer utilities.CloseAndWait(terminate, await)
+	output, _ := utilities.Merge(terminate, outputs...)
+	actual := []int{}
+	for v := range output {
+		actual = append(actual, v)
+	}
+	slices.Sort(actual)
+	if !slices.Equal(actual, []int{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("unexpected %v", actual)
+	}
+}
+
+func TestMapFilter(t *testing.T) {
+	terminate := make(chan any)
+	squares, awaitMap := utilities.Map(terminate, send(1, 2, 3, 4), func(n int) int { return n * n })
+	even, awaitFilter := utilities.Filter(terminate, squares, func(n int) bool { return n%2 == 0 })
+	defer func() {
+		close(terminate)
+		<-awaitMap
+		<-awaitFilter
+	}()
+	actual := []int{}
+	for v := range even {
+		actual = append(actual, v)
+	}
+	if !slices.Equal(actual, []int{4, 16}) {
+		t.Errorf("unexpected %v", actual)
+	}
+}
+
+func TestOrDone(t *testing.T) {
+	terminate := make(chan any)
+	never := make(chan int)
+	output, await := utilities.OrDone(terminate, never)
+	go func() {
+		time.Sleep(time.Millisecond * 5)
+		close(terminate)
+	}()
+	for range output {
+		t.Error("unexpected value")
+	}
+	<-await
+}
@@ -0,0 +1,319 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+
+	// How the fields of a struct type correspond to the columns of a CSV
+	// file.
+	csvBinding struct {
+		fields []csvField
+		width  int
+	}
+
+	// A struct field bound to a CSV column.
+	csvField struct {
+		name   string
+		index  []int
+		column int
+		layout string
+	}
+)
+
+var (
+	durationType = reflect.TypeFor[time.Duration]()
+	timeType     = reflect.TypeFor[time.Time]()
+)
+
+// Return the binding of the exported fields of the given struct type to the
+// given headers. Each field is bound to the column named by its csv tag, or
+// by the field's name if it has none. Fields tagged "-" are ignored. A tag may
+// also specify the layout for a time.Time field, e.g.
+//
+//	When time.Time `csv:"when,layout=2006-01-02"`
+//
+// Embedded struct pointers are allocated as needed when decoding. It is an
+// error for a field's column to be missing from headers, for its type not to
+// be supported by parseCSVValue or for it to be promoted through an embedded
+// pointer to an unexported struct type, which cannot be allocated.
+func newCSVBinding(t reflect.Type, headers []string) (binding *csvBinding, err error) {
+
+	if t.Kind() != reflect.Struct {
+		err = fmt.Errorf("%s is not a struct", t)
+		return
+	}
+
+	binding = &csvBinding{width: len(headers)}
+	var errs []error
+
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		field := csvField{name: f.Name, index: f.Index, layout: time.RFC3339}
+		tag := f.Tag.Get("csv")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name != "" {
+			field.name = name
+		}
+		for _, option := range strings.Split(options, ",") {
+			if layout, ok := strings.CutPrefix(option, "layout="); ok {
+				field.layout = layout
+			}
+		}
+		if field.column = slices.Index(headers, field.name); field.column < 0 {
+			errs = append(errs, fmt.Errorf("column %s for field %s is not in %v", field.name, f.Name, headers))
+			continue
+		}
+		if !csvSupported(f.Type) {
+			errs = append(errs, fmt.Errorf("field %s has unsupported type %s", f.Name, f.Type))
+			continue
+		}
+		if embedded := unexportedEmbeddedPointer(t, f.Index); embedded != "" {
+			errs = append(errs, fmt.Errorf("field %s is promoted through embedded pointer to unexported %s", f.Name, embedded))
+			continue
+		}
+		binding.fields = append(binding.fields, field)
+	}
+
+	err = errors.Join(errs...)
+	return
+}
+
+// Set the bound fields of the given struct to the values of the given columns.
+// Returns a *CSVValidationError for each value that could not be converted,
+// joined.
+func (binding *csvBinding) decode(columns []string, value reflect.Value) error {
+
+	var errs []error
+
+	for _, field := range binding.fields {
+		s := columns[field.column]
+		if err := parseCSVValue(allocateFieldByIndex(value, field.index), s, field.layout); err != nil {
+			errs = append(errs, &CSVValidationError{Column: field.name, Value: s, Err: err})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Return the columns for the given struct. Columns without a bound field, or
+// whose field is promoted through a nil embedded pointer, are empty.
+func (binding *csvBinding) encode(value reflect.Value) []string {
+
+	columns := make([]string, binding.width)
+
+	for _, field := range binding.fields {
+		if v, err := value.FieldByIndexErr(field.index); err == nil {
+			columns[field.column] = formatCSVValue(v, field.layout)
+		}
+	}
+
+	return columns
+}
+
+// Like reflect.Value.FieldByIndex, but allocate any nil embedded struct
+// pointers along the way rather than panicking.
+func allocateFieldByIndex(value reflect.Value, index []int) reflect.Value {
+
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+
+	return value
+}
+
+// Return the name of the first embedded pointer to an unexported struct type
+// on the path to the field of t with the given index, or "" if there is none.
+func unexportedEmbeddedPointer(t reflect.Type, index []int) string {
+
+	for i := range len(index) - 1 {
+		f := t.FieldByIndex(index[:i+1])
+		if f.Type.Kind() == reflect.Pointer && !f.IsExported() {
+			return f.Type.Elem().Name()
+		}
+	}
+
+	return ""
+}
+
+// Return true if parseCSVValue and formatCSVValue support the given type.
+func csvSupported(t reflect.Type) bool {
+
+	if t.Kind() == reflect.Pointer {
+		return csvSupported(t.Elem())
+	}
+
+	if t == durationType || t == timeType {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// Set the given value by parsing the given string according to its type. An
+// empty string sets a pointer to nil, otherwise a new value is allocated and
+// parsed in turn.
+func parseCSVValue(v reflect.Value, s string, layout string) (err error) {
+
+	switch {
+
+	case v.Kind() == reflect.Pointer:
+		if s == "" {
+			v.SetZero()
+			return
+		}
+		p := reflect.New(v.Type().Elem())
+		if err = parseCSVValue(p.Elem(), s, layout); err == nil {
+			v.Set(p)
+		}
+		return
+
+	case v.Type() == durationType:
+		var d time.Duration
+		if d, err = time.ParseDuration(s); err == nil {
+			v.SetInt(int64(d))
+		}
+		return
+
+	case v.Type() == timeType:
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			v.Set(reflect.ValueOf(t))
+		}
+		return
+	}
+
+	switch v.Kind() {
+
+	case reflect.String:
+		v.SetString(s)
+
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			v.SetBool(b)
+		}
+
+	case reflect.Int:
+		err = parseNumberInto[int](v, s)
+
+	case reflect.Int8:
+		err = parseNumberInto[int8](v, s)
+
+	case reflect.Int16:
+		err = parseNumberInto[int16](v, s)
+
+	case reflect.Int32:
+		err = parseNumberInto[int32](v, s)
+
+	case reflect.Int64:
+		err = parseNumberInto[int64](v, s)
+
+	case reflect.Uint:
+		err = parseNumberInto[uint](v, s)
+
+	case reflect.Uint8:
+		err = parseNumberInto[uint8](v, s)
+
+	case reflect.Uint16:
+		err = parseNumberInto[uint16](v, s)
+
+	case reflect.Uint32:
+		err = parseNumberInto[uint32](v, s)
+
+	case reflect.Uint64:
+		err = parseNumberInto[uint64](v, s)
+
+	case reflect.Float32:
+		err = parseNumberInto[float32](v, s)
+
+	case reflect.Float64:
+		err = parseNumberInto[float64](v, s)
+
+	default:
+		err = fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return
+}
+
+// Set the given value, whose kind corresponds to N, using ParseNumber.
+func parseNumberInto[N Number](v reflect.Value, s string) error {
+
+	n, err := ParseNumber[N](s)
+
+	if err == nil {
+		v.Set(reflect.ValueOf(n).Convert(v.Type()))
+	}
+
+	return err
+}
+
+// Return the string representation of the given value, which is empty for a
+// nil pointer.
+func formatCSVValue(v reflect.Value, layout string) string {
+
+	switch {
+
+	case v.Kind() == reflect.Pointer:
+		if v.IsNil() {
+			return ""
+		}
+		return formatCSVValue(v.Elem(), layout)
+
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+
+	case v.Type() == timeType:
+		return v.Interface().(time.Time).Format(layout)
+	}
+
+	switch v.Kind() {
+
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32)
+
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
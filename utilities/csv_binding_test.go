@@ -0,0 +1,190 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"parasaurolophus/utilities"
+	"strings"
+	"testing"
+	"time"
+)
+
+type (
+	reading struct {
+		Device   string        `csv:"device_id"`
+		Value    float64       `csv:"value"`
+		Count    uint8         `csv:"count"`
+		Online   bool          `csv:"online"`
+		Interval time.Duration `csv:"interval"`
+		When     time.Time     `csv:"when,layout=2006-01-02 15:04"`
+		Offset   *int          `csv:"offset"`
+		Ignored  string        `csv:"-"`
+		location
+	}
+
+	location struct {
+		Room string
+	}
+)
+
+const readingsCSV = `device_id,value,count,online,interval,when,offset,Room
+lamp,1.5,3,true,1m30s,2024-06-01 12:30,-2,kitchen
+fan,0,255,false,0s,2024-06-02 08:00,,attic
+`
+
+func TestCSVStructRoundTrip(t *testing.T) {
+	reader := csv.NewReader(strings.NewReader(readingsCSV))
+	headers, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	generate, err := utilities.MakeCSVStructGenerator[reading](reader, headers, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := bytes.Buffer{}
+	writer := csv.NewWriter(&buffer)
+	if err := writer.Write(headers); err != nil {
+		t.Fatal(err)
+	}
+	consume, err := utilities.MakeCSVStructConsumer[reading](writer, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readings := []reading{}
+	transform := func(_ context.Context, r reading) (reading, error) {
+		return r, nil
+	}
+	collect := func(ctx context.Context, r reading) error {
+		readings = append(readings, r)
+		return consume(ctx, r)
+	}
+	options := utilities.BatchOptions{NumTransformers: 2, Ordered: true}
+	func() {
+		defer writer.Flush()
+		err = utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, collect)
+	}()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readings) != 2 {
+		t.Fatalf("expected 2 readings, got %d", len(readings))
+	}
+	lamp := readings[0]
+	when := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+	if lamp.Device != "lamp" || lamp.Value != 1.5 || lamp.Count != 3 || !lamp.Online || lamp.Interval != time.Second*90 || !lamp.When.Equal(when) || lamp.Offset == nil || *lamp.Offset != -2 || lamp.Room != "kitchen" {
+		t.Errorf("unexpected %+v", lamp)
+	}
+	if readings[1].Offset != nil {
+		t.Errorf("expected nil offset, got %d", *readings[1].Offset)
+	}
+	if actual := buffer.String(); actual != readingsCSV {
+		t.Errorf("expected\n%s\ngot\n%s", readingsCSV, actual)
+	}
+}
+
+func TestCSVStructInvalid(t *testing.T) {
+	reader := csv.NewReader(strings.NewReader("device_id,count\nlamp,3\nfan,256\n"))
+	headers, _ := reader.Read()
+	type counter struct {
+		Device string `csv:"device_id"`
+		Count  uint8  `csv:"count"`
+	}
+	errs := []error{}
+	generate, err := utilities.MakeCSVStructGenerator[counter](reader, headers, 1, func(err error) { errs = append(errs, err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	counters := []counter{}
+	err = utilities.ProcessBatchWithErrors(
+		context.Background(),
+		utilities.BatchOptions{NumTransformers: 1},
+		generate,
+		func(_ context.Context, c counter) (counter, error) { return c, nil },
+		func(_ context.Context, c counter) error { counters = append(counters, c); return nil },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counters) != 1 || counters[0].Device != "lamp" {
+		t.Errorf("unexpected %v", counters)
+	}
+	var validationError *utilities.CSVValidationError
+	if len(errs) != 1 || !errors.As(errs[0], &validationError) || validationError.Column != "count" || validationError.Value != "256" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
+
+func TestCSVStructBindingErrors(t *testing.T) {
+	if _, err := utilities.MakeCSVStructConsumer[reading](nil, []string{"device_id"}); err == nil {
+		t.Error("expected missing columns")
+	}
+	if _, err := utilities.MakeCSVStructGenerator[int](nil, nil, 1, nil); err == nil {
+		t.Error("expected an error for a non-struct type")
+	}
+}
+
+func TestCSVStructEmbeddedPointer(t *testing.T) {
+	type Base struct {
+		Device string `csv:"device_id"`
+	}
+	type row struct {
+		*Base
+		Count int `csv:"count"`
+	}
+	reader := csv.NewReader(strings.NewReader("device_id,count\nlamp,3\n"))
+	headers, _ := reader.Read()
+	generate, err := utilities.MakeCSVStructGenerator[row](reader, headers, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []row{}
+	err = utilities.ProcessBatchWithErrors(
+		context.Background(),
+		utilities.BatchOptions{NumTransformers: 1},
+		generate,
+		func(_ context.Context, r row) (row, error) { return r, nil },
+		func(_ context.Context, r row) error { rows = append(rows, r); return nil },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Base == nil || rows[0].Device != "lamp" || rows[0].Count != 3 {
+		t.Fatalf("unexpected %+v", rows)
+	}
+	buffer := bytes.Buffer{}
+	writer := csv.NewWriter(&buffer)
+	consume, err := utilities.MakeCSVStructConsumer[row](writer, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := consume(context.Background(), row{Count: 4}); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+	if actual := buffer.String(); actual != ",4\n" {
+		t.Errorf("expected an empty column for the nil embedded pointer, got %q", actual)
+	}
+}
+
+func TestCSVStructUnsupportedFields(t *testing.T) {
+	type unsupported struct {
+		Tags []string `csv:"tags"`
+	}
+	if _, err := utilities.MakeCSVStructGenerator[unsupported](nil, []string{"tags"}, 1, nil); err == nil {
+		t.Error("expected an error for an unsupported field type")
+	}
+	type hidden struct {
+		Device string `csv:"device_id"`
+	}
+	type row struct {
+		*hidden
+	}
+	if _, err := utilities.MakeCSVStructGenerator[row](nil, []string{"device_id"}, 1, nil); err == nil {
+		t.Error("expected an error for a field promoted through an unexported embedded pointer")
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+
+	// Progress of a CSV batch as persisted by a CSVCheckpointer. Row is the
+	// highest row number such that it and every row before it has been
+	// completed. Offset is the size of the output once those rows, and no
+	// others, have been written.
+	//
+	// See CSVCheckpointer
+	CSVCheckpoint struct {
+		Row    int   `json:"row"`
+		Offset int64 `json:"offset"`
+	}
+
+	// Parameters for NewCSVCheckpointer.
+	//
+	// See NewCSVCheckpointer
+	CSVCheckpointOptions struct {
+
+		// Path of the sidecar file to which checkpoints are written.
+		Path string
+
+		// Minimum time between checkpoints. A checkpoint is written each
+		// time a row is completed if zero.
+		Interval time.Duration
+
+		// Whether to continue from the checkpoint in Path, if there is one,
+		// rather than starting over.
+		Resume bool
+
+		// The ErrorMode of the batch. Rows whose transform fails are
+		// recorded as completed only in CollectAll mode, where they are
+		// reported without ending the batch. In FailFast mode, the row
+		// which ended the batch is retried when it is resumed.
+		ErrorMode ErrorMode
+	}
+
+	// Records how far a CSV batch has got, so that an interrupted run can be
+	// resumed rather than started over. Rows are considered completed once
+	// they have been consumed, they have been skipped or, in CollectAll
+	// mode, their transform has failed. Periodically, the highest
+	// contiguously completed row number and the size of the output up to
+	// and including that row are written to a sidecar file. For example:
+	//
+	//	checkpointer, err := NewCSVCheckpointer(options, reader, output, 1)
+	//	...
+	//	writer := checkpointer.Writer()
+	//	if !checkpointer.Resumed() {
+	//	  writer.Write(headers)
+	//	}
+	//	generate, err := MakeCSVGeneratorWithErrors(reader, headers, checkpointer.StartRow())
+	//	...
+	//	consume := checkpointer.Consume(MakeCSVConsumerWithErrors(writer, headers))
+	//	batchOptions := BatchOptions{NumTransformers: 8, Ordered: true, ErrorMode: CollectAll}
+	//	err = ProcessBatchWithErrors(ctx, batchOptions, generate, checkpointer.Transform(transform), consume)
+	//	err = errors.Join(err, checkpointer.Close())
+	//
+	// The batch must be Ordered so that the output up to each checkpoint
+	// contains exactly the rows it covers, and its ErrorMode must match the
+	// one in the checkpointer's options.
+	//
+	// See CSVCheckpoint, CSVCheckpointOptions, NewCSVCheckpointer
+	CSVCheckpointer struct {
+		lock      sync.Mutex
+		options   CSVCheckpointOptions
+		output    *countingWriter
+		writer    *csv.Writer
+		startRow  int
+		resumed   bool
+		next      int
+		completed map[int]bool
+		saved     time.Time
+		err       error
+	}
+
+	// Counts the bytes written to an underlying writer.
+	countingWriter struct {
+		writer io.Writer
+		count  int64
+	}
+)
+
+// Return a CSVCheckpointer for a batch which reads rows from the given reader,
+// starting with startRow, and writes them to the given output. If
+// options.Resume is true and options.Path contains a checkpoint, the output is
+// truncated to the checkpoint's Offset, discarding any rows written after it,
+// and the rows up to and including its Row are read from reader and
+// discarded. Otherwise, the output is truncated to zero length.
+//
+// See CSVCheckpointer
+func NewCSVCheckpointer(
+
+	options CSVCheckpointOptions,
+	reader *csv.Reader,
+	output *os.File,
+	startRow int,
+
+) (
+
+	checkpointer *CSVCheckpointer,
+	err error,
+
+) {
+
+	checkpoint := CSVCheckpoint{Row: startRow - 1}
+	resumed := false
+
+	if options.Resume {
+		var b []byte
+		b, err = os.ReadFile(options.Path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			err = nil
+		case err != nil:
+			return
+		default:
+			if err = json.Unmarshal(b, &checkpoint); err != nil {
+				return
+			}
+			resumed = true
+		}
+	}
+
+	if err = output.Truncate(checkpoint.Offset); err != nil {
+		return
+	}
+
+	if _, err = output.Seek(checkpoint.Offset, io.SeekStart); err != nil {
+		return
+	}
+
+	for row := startRow; row <= checkpoint.Row; row++ {
+		if _, err = reader.Read(); err != nil {
+			err = fmt.Errorf("skipping row %d: %w", row, err)
+			return
+		}
+	}
+
+	counter := &countingWriter{writer: output, count: checkpoint.Offset}
+
+	checkpointer = &CSVCheckpointer{
+		options:   options,
+		output:    counter,
+		writer:    csv.NewWriter(counter),
+		startRow:  checkpoint.Row + 1,
+		resumed:   resumed,
+		next:      checkpoint.Row + 1,
+		completed: map[int]bool{},
+		saved:     time.Now(),
+	}
+
+	return
+}
+
+// Return the writer to which the batch's output must be written.
+func (checkpointer *CSVCheckpointer) Writer() *csv.Writer {
+
+	return checkpointer.writer
+}
+
+// Return the number of the first row to be processed, for use as the startRow
+// parameter to MakeCSVGeneratorWithErrors.
+func (checkpointer *CSVCheckpointer) StartRow() int {
+
+	return checkpointer.startRow
+}
+
+// Return true if the batch is continuing from a checkpoint, in which case the
+// output already contains any headers.
+func (checkpointer *CSVCheckpointer) Resumed() bool {
+
+	return checkpointer.resumed
+}
+
+// Return a function which invokes the given transform. If options.ErrorMode
+// is CollectAll, the row is recorded as completed if the transform fails,
+// unless its context is done, since failed rows are reported by the batch
+// rather than being retried on resume.
+func (checkpointer *CSVCheckpointer) Transform(
+
+	transform func(context.Context, CSVTransformerParameters) (CSVConsumerParamters, error),
+
+) func(context.Context, CSVTransformerParameters) (CSVConsumerParamters, error) {
+
+	return func(ctx context.Context, input CSVTransformerParameters) (CSVConsumerParamters, error) {
+		output, err := transform(ctx, input)
+		if err != nil && ctx.Err() == nil && checkpointer.options.ErrorMode == CollectAll {
+			checkpointer.Skip(input.Row)
+		}
+		return output, err
+	}
+}
+
+// Return a function which invokes the given consume function then records the
+// row as completed, writing a checkpoint if options.Interval has elapsed since
+// the last one.
+func (checkpointer *CSVCheckpointer) Consume(
+
+	consume func(context.Context, CSVConsumerParamters) error,
+
+) func(context.Context, CSVConsumerParamters) error {
+
+	return func(ctx context.Context, parameters CSVConsumerParamters) (err error) {
+		checkpointer.complete(parameters.Row, func() error {
+			err = consume(ctx, parameters)
+			return err
+		})
+		return
+	}
+}
+
+// Record the given row as completed without writing it to the output, e.g.
+// because it was rejected before reaching a transformer.
+func (checkpointer *CSVCheckpointer) Skip(row int) {
+
+	checkpointer.complete(row, func() error { return nil })
+}
+
+// Return a function which records the row of a *CSVReadError as skipped then
+// passes the error to the given errorHandler, unless it is nil, for use as the
+// errorHandler parameter to MakeCSVGeneratorWithSchema or
+// MakeCSVStructGenerator. Otherwise, the checkpoint could not advance past the
+// first invalid row.
+//
+// See Skip
+func (checkpointer *CSVCheckpointer) HandleError(errorHandler func(error)) func(error) {
+
+	return func(err error) {
+		var readError *CSVReadError
+		if errors.As(err, &readError) {
+			checkpointer.Skip(readError.Row)
+		}
+		if errorHandler != nil {
+			errorHandler(err)
+		}
+	}
+}
+
+// Return the most recent checkpoint, which may not yet have been written.
+func (checkpointer *CSVCheckpointer) Checkpoint() CSVCheckpoint {
+
+	defer checkpointer.lock.Unlock()
+	checkpointer.lock.Lock()
+
+	checkpointer.writer.Flush()
+	return CSVCheckpoint{Row: checkpointer.next - 1, Offset: checkpointer.output.count}
+}
+
+// Flush the output and write a final checkpoint. Returns the first error
+// encountered writing the output or any checkpoint.
+func (checkpointer *CSVCheckpointer) Close() error {
+
+	defer checkpointer.lock.Unlock()
+	checkpointer.lock.Lock()
+
+	checkpointer.save()
+	return checkpointer.err
+}
+
+// Invoke the given function then, unless it fails, record the given row as
+// completed, all while holding the lock so that the output never contains
+// rows beyond the ones recorded.
+func (checkpointer *CSVCheckpointer) complete(row int, fn func() error) {
+
+	defer checkpointer.lock.Unlock()
+	checkpointer.lock.Lock()
+
+	if fn() != nil {
+		return
+	}
+
+	checkpointer.completed[row] = true
+
+	for checkpointer.completed[checkpointer.next] {
+		delete(checkpointer.completed, checkpointer.next)
+		checkpointer.next++
+	}
+
+	if time.Since(checkpointer.saved) >= checkpointer.options.Interval {
+		checkpointer.save()
+	}
+}
+
+// Flush the output and write the current checkpoint to the sidecar file,
+// replacing the previous one atomically. Must be called while holding the
+// lock.
+func (checkpointer *CSVCheckpointer) save() {
+
+	checkpointer.saved = time.Now()
+	checkpointer.writer.Flush()
+
+	if err := checkpointer.writer.Error(); err != nil {
+		checkpointer.err = cmp.Or(checkpointer.err, err)
+		return
+	}
+
+	checkpoint := CSVCheckpoint{Row: checkpointer.next - 1, Offset: checkpointer.output.count}
+	b, err := json.Marshal(checkpoint)
+
+	if err == nil {
+		temp := checkpointer.options.Path + ".tmp"
+		if err = os.WriteFile(temp, b, 0644); err == nil {
+			err = os.Rename(temp, checkpointer.options.Path)
+		}
+	}
+
+	if err != nil {
+		checkpointer.err = cmp.Or(checkpointer.err, err)
+	}
+}
+
+func (writer *countingWriter) Write(p []byte) (n int, err error) {
+
+	n, err = writer.writer.Write(p)
+	writer.count += int64(n)
+	return
+}
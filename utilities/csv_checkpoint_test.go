@@ -0,0 +1,242 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"parasaurolophus/utilities"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// Process the given CSV data, writing to the given output file with a
+// checkpoint, invoking interrupt for each row and failing the row if it
+// returns an error.
+func runCheckpointed(t *testing.T, data string, output *os.File, options utilities.CSVCheckpointOptions, interrupt func(context.CancelFunc, int) error) (*utilities.CSVCheckpointer, error) {
+	reader := csv.NewReader(strings.NewReader(data))
+	headers, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpointer, err := utilities.NewCSVCheckpointer(options, reader, output, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := checkpointer.Writer()
+	if !checkpointer.Resumed() {
+		if err := writer.Write(headers); err != nil {
+			t.Fatal(err)
+		}
+	}
+	generate, err := utilities.MakeCSVGeneratorWithErrors(reader, headers, checkpointer.StartRow())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	transform := func(ctx context.Context, input utilities.CSVTransformerParameters) (output utilities.CSVConsumerParamters, err error) {
+		output.CSVTransformerParameters = input
+		output.Output = input.Input
+		err = cmp.Or(interrupt(cancel, input.Row), ctx.Err())
+		return
+	}
+	consume := checkpointer.Consume(utilities.MakeCSVConsumerWithErrors(writer, headers))
+	batchOptions := utilities.BatchOptions{NumTransformers: 3, Ordered: true}
+	err = utilities.ProcessBatchWithErrors(ctx, batchOptions, generate, checkpointer.Transform(transform), consume)
+	return checkpointer, errors.Join(err, checkpointer.Close())
+}
+
+func TestCSVCheckpointResume(t *testing.T) {
+	builder := strings.Builder{}
+	builder.WriteString("label,number\n")
+	for i := range 20 {
+		fmt.Fprintf(&builder, "row%d,%d\n", i+1, i+1)
+	}
+	data := builder.String()
+	dir := t.TempDir()
+	output, err := os.Create(filepath.Join(dir, "output.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.Close()
+	options := utilities.CSVCheckpointOptions{Path: filepath.Join(dir, "output.csv.checkpoint"), Resume: true}
+	checkpointer, err := runCheckpointed(t, data, output, options, func(cancel context.CancelFunc, row int) error {
+		if row == 12 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	checkpoint := checkpointer.Checkpoint()
+	if checkpoint.Row < 1 || checkpoint.Row >= 12 {
+		t.Fatalf("unexpected checkpoint %+v", checkpoint)
+	}
+	// simulate output written after the last checkpoint
+	if _, err := output.WriteString("partial,row\n"); err != nil {
+		t.Fatal(err)
+	}
+	checkpointer, err = runCheckpointed(t, data, output, options, func(context.CancelFunc, int) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !checkpointer.Resumed() || checkpointer.StartRow() != checkpoint.Row+1 {
+		t.Errorf("expected to resume from row %d, got %d", checkpoint.Row+1, checkpointer.StartRow())
+	}
+	b, err := os.ReadFile(output.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != data {
+		t.Errorf("expected output to match input, got\n%s", b)
+	}
+	if c := checkpointer.Checkpoint(); c.Row != 20 || c.Offset != int64(len(data)) {
+		t.Errorf("unexpected final checkpoint %+v", c)
+	}
+}
+
+func TestCSVCheckpointResumeAfterFailFast(t *testing.T) {
+	data := "label,number\na,1\nb,2\nc,3\nd,4\ne,5\n"
+	dir := t.TempDir()
+	output, err := os.Create(filepath.Join(dir, "output.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.Close()
+	options := utilities.CSVCheckpointOptions{Path: filepath.Join(dir, "output.csv.checkpoint"), Resume: true}
+	failure := errors.New("transient failure")
+	checkpointer, err := runCheckpointed(t, data, output, options, func(_ context.CancelFunc, row int) error {
+		if row == 3 {
+			return failure
+		}
+		return nil
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+	if checkpoint := checkpointer.Checkpoint(); checkpoint.Row >= 3 {
+		t.Fatalf("expected the failed row not to be recorded as completed, got %+v", checkpoint)
+	}
+	retried := atomic.Bool{}
+	_, err = runCheckpointed(t, data, output, options, func(_ context.CancelFunc, row int) error {
+		if row == 3 {
+			retried.Store(true)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !retried.Load() {
+		t.Error("expected the failed row to be retried")
+	}
+	b, err := os.ReadFile(output.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != data {
+		t.Errorf("expected output to match input, got\n%s", b)
+	}
+}
+
+func TestCSVCheckpointFailedRows(t *testing.T) {
+	dir := t.TempDir()
+	output, err := os.Create(filepath.Join(dir, "output.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.Close()
+	reader := csv.NewReader(strings.NewReader("n\n1\n2\n3\n"))
+	headers, _ := reader.Read()
+	options := utilities.CSVCheckpointOptions{Path: filepath.Join(dir, "checkpoint.json"), ErrorMode: utilities.CollectAll}
+	checkpointer, err := utilities.NewCSVCheckpointer(options, reader, output, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generate, _ := utilities.MakeCSVGeneratorWithErrors(reader, headers, checkpointer.StartRow())
+	transform := func(_ context.Context, input utilities.CSVTransformerParameters) (output utilities.CSVConsumerParamters, err error) {
+		output.CSVTransformerParameters = input
+		output.Output = input.Input
+		if input.Row == 2 {
+			err = errors.New("bad row")
+		}
+		return
+	}
+	consume := checkpointer.Consume(utilities.MakeCSVConsumerWithErrors(checkpointer.Writer(), headers))
+	batchOptions := utilities.BatchOptions{NumTransformers: 2, Ordered: true, ErrorMode: utilities.CollectAll}
+	err = utilities.ProcessBatchWithErrors(context.Background(), batchOptions, generate, checkpointer.Transform(transform), consume)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if err := checkpointer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(options.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"row":3,"offset":4}` {
+		t.Errorf("unexpected checkpoint %s", b)
+	}
+}
+
+func TestCSVCheckpointRejectedRows(t *testing.T) {
+	dir := t.TempDir()
+	output, err := os.Create(filepath.Join(dir, "output.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.Close()
+	reader := csv.NewReader(strings.NewReader("label,number\na,1\nb,two\nc,3\nd,\ne,5\n"))
+	headers, _ := reader.Read()
+	options := utilities.CSVCheckpointOptions{Path: filepath.Join(dir, "checkpoint.json")}
+	checkpointer, err := utilities.NewCSVCheckpointer(options, reader, output, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := strings.Builder{}
+	rejects, err := utilities.NewCSVRejects(csv.NewWriter(&rejected), headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := utilities.CSVSchema{{Name: "number", Type: utilities.IntColumn, Required: true}}
+	generate, err := utilities.MakeCSVGeneratorWithSchema(reader, headers, checkpointer.StartRow(), schema, checkpointer.HandleError(rejects.HandleError))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform := func(_ context.Context, input utilities.CSVTransformerParameters) (output utilities.CSVConsumerParamters, err error) {
+		output.CSVTransformerParameters = input
+		output.Output = input.Input
+		return
+	}
+	consume := checkpointer.Consume(utilities.MakeCSVConsumerWithErrors(checkpointer.Writer(), headers))
+	batchOptions := utilities.BatchOptions{NumTransformers: 2, Ordered: true}
+	err = utilities.ProcessBatchWithErrors(context.Background(), batchOptions, generate, checkpointer.Transform(transform), consume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := errors.Join(checkpointer.Close(), rejects.Flush()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(output.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "a,1\nc,3\ne,5\n" {
+		t.Errorf("unexpected output\n%s", b)
+	}
+	if c := checkpointer.Checkpoint(); c.Row != 5 || c.Offset != int64(len(b)) {
+		t.Errorf("expected the checkpoint to cover all 5 rows, got %+v", c)
+	}
+	if lines := strings.Count(rejected.String(), "\n"); lines != 3 {
+		t.Errorf("expected a header and 2 rejected rows, got\n%s", rejected.String())
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+type (
+
+	// A dead-letter sink which writes the rows of a CSV batch that fail, as
+	// they were read, to a separate CSV file so that they can be fixed by
+	// hand and processed again. Each reject is preceded by the columns "row",
+	// "line", "stage" and "error", giving the row number, the line of the
+	// input file on which the row starts, the stage that failed ("read",
+	// "validate", "transform" or "consume") and the error text. For example:
+	//
+	//	rejects, err := NewCSVRejects(csv.NewWriter(rejectsFile), headers)
+	//	...
+	//	generate, err := MakeCSVGeneratorWithErrors(reader, headers, 1)
+	//	...
+	//	err = ProcessBatchWithErrors(
+	//	  ctx,
+	//	  BatchOptions{NumTransformers: 8, ErrorMode: CollectAll},
+	//	  rejects.Generate(generate),
+	//	  rejects.Transform(transform),
+	//	  rejects.Consume(consume),
+	//	)
+	//	err = errors.Join(err, rejects.Flush())
+	//
+	// Rows remain failures of the batch as well as being written to the
+	// sink. A CSVRejects' methods may be invoked concurrently.
+	//
+	// See CSVReadError, NewCSVRejects
+	CSVRejects struct {
+		lock    sync.Mutex
+		writer  *csv.Writer
+		headers []string
+		err     error
+	}
+)
+
+// Return a CSVRejects which writes to the given writer, after writing a row of
+// column headers consisting of "row", "line", "stage" and "error" followed by
+// the given headers of the input file.
+//
+// See CSVRejects
+func NewCSVRejects(
+
+	writer *csv.Writer,
+	headers []string,
+
+) (
+
+	rejects *CSVRejects,
+	err error,
+
+) {
+
+	if err = writer.Write(append([]string{"row", "line", "stage", "error"}, headers...)); err != nil {
+		return
+	}
+
+	rejects = &CSVRejects{writer: writer, headers: headers}
+	return
+}
+
+// Write the given row to the sink as having failed in the given stage with the
+// given error. The row's Columns are written unchanged if they are available,
+// otherwise its Input values are written in header order.
+func (rejects *CSVRejects) Reject(stage string, parameters CSVTransformerParameters, cause error) error {
+
+	columns := parameters.Columns
+
+	if columns == nil && parameters.Input != nil {
+		columns = make([]string, len(rejects.headers))
+		for i, h := range rejects.headers {
+			columns[i] = parameters.Input[h]
+		}
+	}
+
+	record := append(
+		[]string{
+			strconv.Itoa(parameters.Row),
+			strconv.Itoa(parameters.Line),
+			stage,
+			cause.Error(),
+		},
+		columns...,
+	)
+
+	defer rejects.lock.Unlock()
+	rejects.lock.Lock()
+
+	err := rejects.writer.Write(record)
+	rejects.err = cmp.Or(rejects.err, err)
+	return err
+}
+
+// Return a function which invokes the given generate function, writing the row
+// to the sink if it returns a *CSVReadError.
+//
+// See HandleError
+func (rejects *CSVRejects) Generate(
+
+	generate func(context.Context, []chan<- CSVTransformerParameters) error,
+
+) func(context.Context, []chan<- CSVTransformerParameters) error {
+
+	return func(ctx context.Context, transformers []chan<- CSVTransformerParameters) error {
+		err := generate(ctx, transformers)
+		rejects.HandleError(err)
+		return err
+	}
+}
+
+// Write the row to the sink if the given error is a *CSVReadError, for use as
+// the errorHandler parameter to MakeCSVGeneratorWithSchema. The stage is
+// "validate" if the error wraps a *CSVValidationError, otherwise "read".
+func (rejects *CSVRejects) HandleError(err error) {
+
+	var readError *CSVReadError
+
+	if !errors.As(err, &readError) {
+		return
+	}
+
+	stage := "read"
+	var validationError *CSVValidationError
+
+	if errors.As(readError.Err, &validationError) {
+		stage = "validate"
+	}
+
+	rejects.Reject(stage, readError.CSVTransformerParameters, readError.Err)
+}
+
+// Return a function which invokes the given transform, writing the row to the
+// sink if it fails, unless its context is done.
+func (rejects *CSVRejects) Transform(
+
+	transform func(context.Context, CSVTransformerParameters) (CSVConsumerParamters, error),
+
+) func(context.Context, CSVTransformerParameters) (CSVConsumerParamters, error) {
+
+	return func(ctx context.Context, input CSVTransformerParameters) (CSVConsumerParamters, error) {
+		output, err := transform(ctx, input)
+		if err != nil && ctx.Err() == nil {
+			rejects.Reject("transform", input, err)
+		}
+		return output, err
+	}
+}
+
+// Return a function which invokes the given consume function, writing the row
+// to the sink if it fails, unless its context is done.
+func (rejects *CSVRejects) Consume(
+
+	consume func(context.Context, CSVConsumerParamters) error,
+
+) func(context.Context, CSVConsumerParamters) error {
+
+	return func(ctx context.Context, parameters CSVConsumerParamters) error {
+		err := consume(ctx, parameters)
+		if err != nil && ctx.Err() == nil {
+			rejects.Reject("consume", parameters.CSVTransformerParameters, err)
+		}
+		return err
+	}
+}
+
+// Flush the sink's writer. Returns the first error encountered writing any of
+// the rejects.
+func (rejects *CSVRejects) Flush() error {
+
+	defer rejects.lock.Unlock()
+	rejects.lock.Lock()
+
+	rejects.writer.Flush()
+	return cmp.Or(rejects.err, rejects.writer.Error())
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"parasaurolophus/utilities"
+	"strings"
+	"testing"
+)
+
+// Process the named embedded CSV file, returning the contents of the rejects
+// file.
+func processWithRejects(t *testing.T, name string) string {
+	b, err := embedded.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvReader := csv.NewReader(bytes.NewReader(b))
+	headers, err := csvReader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	generate, err := utilities.MakeCSVGeneratorWithErrors(csvReader, headers, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform := func(_ context.Context, input utilities.CSVTransformerParameters) (output utilities.CSVConsumerParamters, err error) {
+		output.CSVTransformerParameters = input
+		output.Output = input.Input
+		_, err = utilities.ParseNumber[int](input.Input["number"])
+		return
+	}
+	consume := func(context.Context, utilities.CSVConsumerParamters) error {
+		return nil
+	}
+	buffer := bytes.Buffer{}
+	rejects, err := utilities.NewCSVRejects(csv.NewWriter(&buffer), headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := utilities.BatchOptions{NumTransformers: 2, ErrorMode: utilities.CollectAll}
+	err = utilities.ProcessBatchWithErrors(
+		context.Background(),
+		options,
+		rejects.Generate(generate),
+		rejects.Transform(transform),
+		rejects.Consume(consume),
+	)
+	if err == nil {
+		t.Error("expected an error")
+	}
+	if err := rejects.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.String()
+}
+
+func TestCSVRejectsTransform(t *testing.T) {
+	actual := processWithRejects(t, "embedded/inconsistent.csv")
+	if !strings.HasPrefix(actual, "row,line,stage,error,label,number\n3,4,transform,") || !strings.HasSuffix(actual, ",two,dos\n") {
+		t.Errorf("unexpected rejects\n%s", actual)
+	}
+}
+
+func TestCSVRejectsRead(t *testing.T) {
+	actual := processWithRejects(t, "embedded/malformed.csv")
+	expected := "row,line,stage,error,label,number\n3,4,read,record on line 4: wrong number of fields,two\n"
+	if actual != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestCSVReadError(t *testing.T) {
+	csvReader := csv.NewReader(strings.NewReader("a,b\n1,2\n\"3,4\n"))
+	rows, _ := utilities.MakeCSVSeq(csvReader, []string{"a", "b"}, 1)
+	failed := false
+	for _, err := range rows {
+		var readError *utilities.CSVReadError
+		if err == nil {
+			continue
+		}
+		failed = true
+		if !errors.As(err, &readError) || readError.Row != 3 || readError.Line != 3 || readError.Columns != nil {
+			t.Errorf("unexpected error %#v", err)
+		}
+	}
+	if !failed {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+type (
+
+	// Type to which the values of a CSVColumn are converted.
+	//
+	// See CSVColumn
+	ColumnType int
+
+	// Declaration of a column of a CSV file, for validating and converting
+	// its values. Values are converted to string, int64, float64 or bool
+	// according to Type.
+	//
+	// See CSVSchema
+	CSVColumn struct {
+
+		// Header of the column.
+		Name string
+
+		// Type to which the column's values are converted.
+		Type ColumnType
+
+		// Whether an empty value is invalid. An empty value in an optional
+		// column is replaced by Default, if that is not empty, otherwise the
+		// column is omitted from the converted values.
+		Required bool
+		Default  string
+
+		// Inclusive bounds, if not nil, on the values of numeric columns.
+		Min *float64
+		Max *float64
+
+		// If not nil, a pattern which values must match.
+		Pattern *regexp.Regexp
+
+		// If not empty, the only values allowed.
+		Enum []string
+	}
+
+	// The columns of a CSV file to be validated and converted. Columns not
+	// declared in the schema are neither validated nor converted.
+	//
+	// See CSVColumn, MakeCSVGeneratorWithSchema
+	CSVSchema []CSVColumn
+
+	// Error for an invalid value in a CSV column.
+	//
+	// See CSVSchema
+	CSVValidationError struct {
+		Column string
+		Value  string
+		Err    error
+	}
+)
+
+const (
+
+	// Values are strings.
+	StringColumn = ColumnType(iota)
+
+	// Values are parsed as int64.
+	IntColumn
+
+	// Values are parsed as float64.
+	FloatColumn
+
+	// Values are parsed as bool using strconv.ParseBool.
+	BoolColumn
+)
+
+var (
+
+	// Reported for an empty value in a required column.
+	ErrRequired = errors.New("required value is missing")
+
+	// Reported for a numeric value outside of a column's bounds.
+	ErrOutOfRange = errors.New("value out of range")
+
+	// Reported for a value which does not match a column's pattern.
+	ErrNoMatch = errors.New("value does not match pattern")
+
+	// Reported for a value which is not one of a column's enumerated values.
+	ErrNotAllowed = errors.New("value not allowed")
+)
+
+func (err *CSVValidationError) Error() string {
+
+	return fmt.Sprintf("column %s: invalid value %q: %s", err.Column, err.Value, err.Err.Error())
+}
+
+func (err *CSVValidationError) Unwrap() error {
+
+	return err.Err
+}
+
+// Return an error if any of the schema's columns are missing from the given
+// headers.
+func (schema CSVSchema) Check(headers []string) error {
+
+	var errs []error
+
+	for _, column := range schema {
+		if !slices.Contains(headers, column.Name) {
+			errs = append(errs, fmt.Errorf("column %s is not in %v", column.Name, headers))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate the given row, returning its values converted according to the
+// schema. Returns a *CSVValidationError for each invalid value, joined.
+func (schema CSVSchema) Validate(input map[string]string) (values map[string]any, err error) {
+
+	values = make(map[string]any, len(schema))
+	var errs []error
+
+	for _, column := range schema {
+		s := input[column.Name]
+		if s == "" {
+			if column.Required {
+				errs = append(errs, &CSVValidationError{Column: column.Name, Err: ErrRequired})
+				continue
+			}
+			if s = column.Default; s == "" {
+				continue
+			}
+		}
+		value, e := column.convert(s)
+		if e != nil {
+			errs = append(errs, &CSVValidationError{Column: column.Name, Value: s, Err: e})
+			continue
+		}
+		values[column.Name] = value
+	}
+
+	err = errors.Join(errs...)
+	return
+}
+
+// Check the given value against the column's constraints and convert it to
+// the column's type.
+func (column CSVColumn) convert(s string) (value any, err error) {
+
+	if len(column.Enum) > 0 && !slices.Contains(column.Enum, s) {
+		err = ErrNotAllowed
+		return
+	}
+
+	if column.Pattern != nil && !column.Pattern.MatchString(s) {
+		err = ErrNoMatch
+		return
+	}
+
+	var number float64
+
+	switch column.Type {
+
+	case IntColumn:
+		var i int64
+		if i, err = ParseNumber[int64](s); err != nil {
+			return
+		}
+		value = i
+		number = float64(i)
+
+	case FloatColumn:
+		if number, err = ParseNumber[float64](s); err != nil {
+			return
+		}
+		value = number
+
+	case BoolColumn:
+		value, err = strconv.ParseBool(s)
+		return
+
+	default:
+		value = s
+		return
+	}
+
+	if (column.Min != nil && number < *column.Min) || (column.Max != nil && number > *column.Max) {
+		err = ErrOutOfRange
+	}
+
+	return
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"parasaurolophus/utilities"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCSVSchemaValidate(t *testing.T) {
+	zero, hundred := 0.0, 100.0
+	schema := utilities.CSVSchema{
+		{Name: "label", Required: true, Pattern: regexp.MustCompile(`^[a-z]+$`)},
+		{Name: "count", Type: utilities.IntColumn, Min: &zero, Max: &hundred},
+		{Name: "ratio", Type: utilities.FloatColumn, Default: "0.5"},
+		{Name: "enabled", Type: utilities.BoolColumn},
+		{Name: "color", Enum: []string{"red", "green"}},
+	}
+	values, err := schema.Validate(map[string]string{"label": "abc", "count": "42", "enabled": "true", "color": "red"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["label"] != "abc" || values["count"] != int64(42) || values["ratio"] != 0.5 || values["enabled"] != true || values["color"] != "red" {
+		t.Errorf("unexpected values %v", values)
+	}
+	_, err = schema.Validate(map[string]string{"label": "", "count": "101", "ratio": "x", "color": "blue"})
+	for _, expected := range []error{utilities.ErrRequired, utilities.ErrOutOfRange, utilities.ErrNotAllowed} {
+		if !errors.Is(err, expected) {
+			t.Errorf("expected %v in %v", expected, err)
+		}
+	}
+	var validationError *utilities.CSVValidationError
+	if !errors.As(err, &validationError) || validationError.Column != "label" {
+		t.Errorf("expected the first error to be for label, got %v", err)
+	}
+	if _, err = schema.Validate(map[string]string{"label": "ABC"}); !errors.Is(err, utilities.ErrNoMatch) {
+		t.Errorf("expected %v, got %v", utilities.ErrNoMatch, err)
+	}
+	if err := schema.Check([]string{"label", "count"}); err == nil {
+		t.Error("expected missing columns")
+	}
+}
+
+func TestMakeCSVGeneratorWithSchema(t *testing.T) {
+	b, err := embedded.ReadFile("embedded/inconsistent.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvReader := csv.NewReader(bytes.NewReader(b))
+	headers, err := csvReader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := bytes.Buffer{}
+	rejects, err := utilities.NewCSVRejects(csv.NewWriter(&buffer), headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := utilities.CSVSchema{{Name: "number", Type: utilities.IntColumn, Required: true}}
+	generate, err := utilities.MakeCSVGeneratorWithSchema(csvReader, headers, 1, schema, rejects.HandleError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform := func(_ context.Context, input utilities.CSVTransformerParameters) (int64, error) {
+		return input.Values["number"].(int64), nil
+	}
+	total := int64(0)
+	consume := func(_ context.Context, n int64) error {
+		total += n
+		return nil
+	}
+	options := utilities.BatchOptions{NumTransformers: 2}
+	if err := utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume); err != nil {
+		t.Fatal(err)
+	}
+	if total != 13 {
+		t.Errorf("expected 13, got %d", total)
+	}
+	if err := rejects.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if actual := buffer.String(); !strings.Contains(actual, "\n3,4,validate,") || !strings.HasSuffix(actual, ",two,dos\n") {
+		t.Errorf("unexpected rejects\n%s", actual)
+	}
+}
+
+func TestMakeCSVGeneratorWithSchemaMissingColumn(t *testing.T) {
+	csvReader := csv.NewReader(strings.NewReader(""))
+	schema := utilities.CSVSchema{{Name: "missing"}}
+	if _, err := utilities.MakeCSVGeneratorWithSchema(csvReader, []string{"label"}, 1, schema, nil); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -4,11 +4,14 @@ package utilities_test
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"parasaurolophus/utilities"
 	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -197,3 +200,219 @@ func TestProcessBatchCSVMalformed(t *testing.T) {
 		t.Errorf("expected 1 error, got %d", errors)
 	}
 }
+
+func TestProcessBatchCSVContextCanceled(t *testing.T) {
+	inputData, err := embedded.ReadFile("embedded/input.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := bytes.NewReader(inputData)
+	csvReader := csv.NewReader(reader)
+	headers, err := csvReader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errors := 0
+	errorHandler := func(error) {
+		errors += 1
+	}
+	generate, err := utilities.MakeCSVGeneratorContext(csvReader, headers, 1, errorHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	transform := func(_ context.Context, input utilities.CSVTransformerParameters) (output utilities.CSVConsumerParamters) {
+		output = utilities.CSVConsumerParamters{}
+		output.CSVTransformerParameters = input
+		output.Output = input.Input
+		return
+	}
+	buffer := bytes.Buffer{}
+	writer := csv.NewWriter(&buffer)
+	consumer := utilities.MakeCSVConsumer(writer, headers, errorHandler)
+	// cancel once the first row has been written, so that any row received
+	// by the consumer after that is dropped
+	consume := func(_ context.Context, parameters utilities.CSVConsumerParamters) {
+		consumer(parameters)
+		if parameters.Row == 1 {
+			cancel()
+		}
+	}
+	func() {
+		defer writer.Flush()
+		err = utilities.ProcessBatchContext(ctx, 1, 0, 0, generate, transform, consume)
+	}()
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if errors != 0 {
+		t.Errorf("expected no errors, got %d", errors)
+	}
+	if rows, _ := csv.NewReader(&buffer).ReadAll(); len(rows) != 1 {
+		t.Errorf("expected 1 row, got %d", len(rows))
+	}
+}
+
+func TestProcessBatchCSVWithErrors(t *testing.T) {
+	b, err := embedded.ReadFile("embedded/inconsistent.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := bytes.NewReader(b)
+	csvReader := csv.NewReader(reader)
+	headers, err := csvReader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	generate, err := utilities.MakeCSVGeneratorWithErrors(csvReader, headers, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform := func(_ context.Context, input utilities.CSVTransformerParameters) (output utilities.CSVConsumerParamters, err error) {
+		output.CSVTransformerParameters = input
+		output.Output = input.Input
+		_, err = utilities.ParseNumber[int](input.Input["number"])
+		return
+	}
+	buffer := bytes.Buffer{}
+	writer := csv.NewWriter(&buffer)
+	consume := utilities.MakeCSVConsumerWithErrors(writer, headers)
+	options := utilities.BatchOptions{
+		NumTransformers: 3,
+		ErrorMode:       utilities.CollectAll,
+	}
+	func() {
+		defer writer.Flush()
+		err = utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume)
+	}()
+	var itemError *utilities.ItemError
+	if !errors.As(err, &itemError) {
+		t.Fatalf("expected an ItemError, got %v", err)
+	}
+	if row := itemError.Index + 1; row != 3 {
+		t.Errorf("expected row 3 to fail, got %d", row)
+	}
+	if rows, _ := csv.NewReader(&buffer).ReadAll(); len(rows) != 5 {
+		t.Errorf("expected 5 rows, got %d", len(rows))
+	}
+}
+
+func TestProcessBatchCSVOrdered(t *testing.T) {
+	inputData, err := embedded.ReadFile("embedded/input.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvReader := csv.NewReader(bytes.NewReader(inputData))
+	headers, err := csvReader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	generate, err := utilities.MakeCSVGeneratorWithErrors(csvReader, headers, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform := func(_ context.Context, input utilities.CSVTransformerParameters) (output utilities.CSVConsumerParamters, err error) {
+		output.CSVTransformerParameters = input
+		output.Output = input.Input
+		return
+	}
+	buffer := bytes.Buffer{}
+	writer := csv.NewWriter(&buffer)
+	err = writer.Write(headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	consume := utilities.MakeCSVConsumerWithErrors(writer, headers)
+	options := utilities.BatchOptions{
+		NumTransformers: 3,
+		Ordered:         true,
+	}
+	func() {
+		defer writer.Flush()
+		err = utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume)
+	}()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(inputData, buffer.Bytes()) {
+		t.Errorf("expected output to match input, got\n%s", buffer.String())
+	}
+}
+
+func TestProcessBatchCSVPartitioned(t *testing.T) {
+	inputData, err := embedded.ReadFile("embedded/input.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvReader := csv.NewReader(bytes.NewReader(inputData))
+	headers, err := csvReader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(parameters utilities.CSVTransformerParameters) string {
+		return parameters.Input["label"]
+	}
+	generate, err := utilities.MakePartitionedCSVGenerator(csvReader, headers, 1, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := 0
+	lock := sync.Mutex{}
+	transform := func(_ context.Context, input utilities.CSVTransformerParameters) (output utilities.CSVConsumerParamters, err error) {
+		output.CSVTransformerParameters = input
+		output.Output = input.Input
+		n, err := utilities.ParseNumber[int](input.Input["number"])
+		defer lock.Unlock()
+		lock.Lock()
+		actual += n
+		return
+	}
+	consume := func(context.Context, utilities.CSVConsumerParamters) error {
+		return nil
+	}
+	options := utilities.BatchOptions{NumTransformers: 3}
+	err = utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != 15 {
+		t.Errorf("expected 15, got %d", actual)
+	}
+}
+
+func TestCSVSeq(t *testing.T) {
+	for _, mode := range []utilities.ErrorMode{utilities.FailFast, utilities.CollectAll} {
+		b, err := embedded.ReadFile("embedded/malformed.csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		csvReader := csv.NewReader(bytes.NewReader(b))
+		headers, err := csvReader.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := utilities.MakeCSVSeq(csvReader, headers, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transform := func(_ context.Context, input utilities.CSVTransformerParameters) (int, error) {
+			return utilities.ParseNumber[int](input.Input["number"])
+		}
+		options := utilities.BatchOptions{NumTransformers: 2, Ordered: true, ErrorMode: mode}
+		outputs, batchErr := utilities.ProcessSeq2(context.Background(), options, rows, transform)
+		actual := []int{}
+		for n := range outputs {
+			actual = append(actual, n)
+		}
+		var itemError *utilities.ItemError
+		if !errors.As(batchErr(), &itemError) || itemError.Index != 2 {
+			t.Errorf("mode %d: expected an ItemError for index 2, got %v", mode, batchErr())
+		}
+		// in FailFast mode, the error may cancel the batch before the rows
+		// preceding the malformed one are consumed
+		if mode == utilities.CollectAll && (len(actual) != 2 || actual[0] != 0 || actual[1] != 1) {
+			t.Errorf("mode %d: unexpected %v", mode, actual)
+		}
+	}
+}
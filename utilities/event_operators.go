@@ -0,0 +1,219 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"time"
+)
+
+// Forward only the last of each burst of values received from input to the
+// returned output channel, once no further value has been received for the
+// given quiet period, until input or terminate is closed. A pending value is
+// sent before the output channel is closed if input is closed, but not if
+// terminate is. The returned output channel is closed, followed by the returned
+// await channel, when the forwarding goroutine exits.
+//
+// See Coalesce, OrDone, Throttle
+func Debounce[V any](
+
+	terminate <-chan any,
+	input <-chan V,
+	quiet time.Duration,
+
+) (
+
+	output <-chan V,
+	await <-chan any,
+
+) {
+
+	o := make(chan V)
+	a := make(chan any)
+	output = o
+	await = a
+
+	go func() {
+
+		defer close(a)
+		defer close(o)
+
+		var (
+			pending V
+			timer   <-chan time.Time
+		)
+
+		for {
+
+			select {
+
+			case value, ok := <-input:
+				if !ok {
+					if timer != nil {
+						sendOrDone(terminate, o, pending)
+					}
+					return
+				}
+				pending = value
+				timer = time.After(quiet)
+
+			case <-timer:
+				timer = nil
+				if !sendOrDone(terminate, o, pending) {
+					return
+				}
+
+			case <-terminate:
+				return
+			}
+		}
+	}()
+
+	return
+}
+
+// Forward at most n of the values received from input in each interval to the
+// returned output channel, discarding the rest, until input or terminate is
+// closed. Each interval starts with the first value received after the end of
+// the previous one. Use Coalesce or Debounce instead where the latest value
+// must not be discarded. The returned output channel is closed, followed by
+// the returned await channel, when the forwarding goroutine exits.
+//
+// See Coalesce, Debounce, Limiter
+func Throttle[V any](
+
+	terminate <-chan any,
+	input <-chan V,
+	n int,
+	interval time.Duration,
+
+) (
+
+	output <-chan V,
+	await <-chan any,
+
+) {
+
+	var (
+		end   time.Time
+		count int
+	)
+
+	return Filter(terminate, input, func(V) bool {
+		if now := time.Now(); !now.Before(end) {
+			end = now.Add(interval)
+			count = 0
+		}
+		if count >= n {
+			return false
+		}
+		count++
+		return true
+	})
+}
+
+// Forward only the latest of the values received from input with each key
+// within each window to the returned output channel, until input or terminate
+// is closed. Each window starts with the first value received after the end of
+// the previous one. At the end of each window, the values it collected are
+// sent in the order in which their keys were first received within it. For
+// example, to pass on only the latest update to each hue resource every 100
+// milliseconds:
+//
+//	items, errors, bridgeTerminate, bridgeAwait, err := bridge.Subscribe(onConnect, onDisconnect)
+//	...
+//	terminate := make(chan any)
+//	updates, await := Coalesce(terminate, items, time.Millisecond*100, hue.Item.Id)
+//	defer CloseAndWait(terminate, await)
+//
+// Pending values are sent before the output channel is closed if input is
+// closed, but not if terminate is. The returned output channel is closed,
+// followed by the returned await channel, when the forwarding goroutine exits.
+//
+// See Debounce, Throttle
+func Coalesce[V any, K comparable](
+
+	terminate <-chan any,
+	input <-chan V,
+	window time.Duration,
+	key func(V) K,
+
+) (
+
+	output <-chan V,
+	await <-chan any,
+
+) {
+
+	o := make(chan V)
+	a := make(chan any)
+	output = o
+	await = a
+
+	go func() {
+
+		defer close(a)
+		defer close(o)
+
+		var (
+			indices = map[K]int{}
+			pending []V
+			timer   <-chan time.Time
+		)
+
+		flush := func() bool {
+			defer clear(indices)
+			for _, value := range pending {
+				if !sendOrDone(terminate, o, value) {
+					return false
+				}
+			}
+			pending = pending[:0]
+			return true
+		}
+
+		for {
+
+			select {
+
+			case value, ok := <-input:
+				if !ok {
+					flush()
+					return
+				}
+				k := key(value)
+				if i, found := indices[k]; found {
+					pending[i] = value
+				} else {
+					indices[k] = len(pending)
+					pending = append(pending, value)
+				}
+				if timer == nil {
+					timer = time.After(window)
+				}
+
+			case <-timer:
+				timer = nil
+				if !flush() {
+					return
+				}
+
+			case <-terminate:
+				return
+			}
+		}
+	}()
+
+	return
+}
+
+// Send the given value to output unless terminate is closed first. Returns
+// false if terminate was closed.
+func sendOrDone[V any](terminate <-chan any, output chan<- V, value V) bool {
+
+	select {
+	case output <- value:
+		return true
+	case <-terminate:
+		return false
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"parasaurolophus/utilities"
+	"slices"
+	"testing"
+	"time"
+)
+
+func collect[V any](output <-chan V) []V {
+	values := []V{}
+	for v := range output {
+		values = append(values, v)
+	}
+	return values
+}
+
+func TestDebounce(t *testing.T) {
+	input := make(chan int)
+	terminate := make(chan any)
+	output, await := utilities.Debounce(terminate, input, time.Millisecond*20)
+	defer utilities.CloseAndWait(terminate, await)
+	go func() {
+		defer close(input)
+		for i := range 5 {
+			input <- i
+		}
+		time.Sleep(time.Millisecond * 50)
+		input <- 5
+		input <- 6
+	}()
+	if actual := collect(output); !slices.Equal(actual, []int{4, 6}) {
+		t.Errorf("expected [4 6], got %v", actual)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	input := make(chan int)
+	terminate := make(chan any)
+	output, await := utilities.Throttle(terminate, input, 2, time.Millisecond*30)
+	defer utilities.CloseAndWait(terminate, await)
+	go func() {
+		defer close(input)
+		for i := range 5 {
+			input <- i
+		}
+		time.Sleep(time.Millisecond * 50)
+		for i := range 5 {
+			input <- 10 + i
+		}
+	}()
+	if actual := collect(output); !slices.Equal(actual, []int{0, 1, 10, 11}) {
+		t.Errorf("expected [0 1 10 11], got %v", actual)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	type update struct {
+		id    string
+		value int
+	}
+	input := make(chan update)
+	terminate := make(chan any)
+	output, await := utilities.Coalesce(terminate, input, time.Millisecond*20, func(u update) string { return u.id })
+	defer utilities.CloseAndWait(terminate, await)
+	go func() {
+		defer close(input)
+		input <- update{"a", 1}
+		input <- update{"b", 1}
+		input <- update{"a", 2}
+		time.Sleep(time.Millisecond * 50)
+		input <- update{"b", 2}
+		input <- update{"b", 3}
+	}()
+	expected := []update{{"a", 2}, {"b", 1}, {"b", 3}}
+	if actual := collect(output); !slices.Equal(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCoalesceTerminate(t *testing.T) {
+	input := make(chan int)
+	terminate := make(chan any)
+	output, await := utilities.Coalesce(terminate, input, time.Hour, func(n int) int { return n })
+	input <- 1
+	close(terminate)
+	<-await
+	if actual := collect(output); len(actual) != 0 {
+		t.Errorf("expected nothing, got %v", actual)
+	}
+}
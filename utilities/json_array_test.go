@@ -0,0 +1,110 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"parasaurolophus/utilities"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Run a batch over the elements of the given JSON document, returning the
+// values and errors received.
+func processJSONArray(t *testing.T, document string, path ...string) (values []int, errs []error) {
+	errorHandler := func(err error) {
+		errs = append(errs, err)
+	}
+	generate, err := utilities.MakeJSONArrayGenerator[int](strings.NewReader(document), errorHandler, path...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform := func(input utilities.JSONArrayTransformerParameters[int]) int {
+		return input.Value
+	}
+	consume := func(n int) {
+		values = append(values, n)
+	}
+	utilities.ProcessBatch(2, 0, 0, generate, transform, consume)
+	slices.Sort(values)
+	return
+}
+
+func TestJSONArrayTopLevel(t *testing.T) {
+	values, errs := processJSONArray(t, `[1, 2, "three", 4] trailing garbage`)
+	if !slices.Equal(values, []int{1, 2, 4}) {
+		t.Errorf("unexpected %v", values)
+	}
+	var itemError *utilities.ItemError
+	if len(errs) != 1 || !errors.As(errs[0], &itemError) || itemError.Index != 2 {
+		t.Errorf("expected an error for index 2, got %v", errs)
+	}
+}
+
+func TestJSONArrayPath(t *testing.T) {
+	document := `{"errors": [], "meta": {"skip": [1, {"a": [2]}]}, "result": {"data": [5, 6, 7]}}`
+	values, errs := processJSONArray(t, document, "result", "data")
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if !slices.Equal(values, []int{5, 6, 7}) {
+		t.Errorf("unexpected %v", values)
+	}
+}
+
+func TestJSONArrayErrors(t *testing.T) {
+	for _, test := range []struct {
+		document string
+		path     []string
+		expected string
+	}{
+		{`{"data": [1]}`, []string{"missing"}, `no value found for "missing"`},
+		{`{"data": {"x": 1}}`, []string{"data"}, `data: expected [, found {`},
+		{`[1, 2`, nil, `unexpected end of JSON input`},
+		{`{"data": 1}`, nil, `expected [, found {`},
+	} {
+		_, errs := processJSONArray(t, test.document, test.path...)
+		if len(errs) != 1 || errs[0].Error() != test.expected {
+			t.Errorf("%s: expected %q, got %v", test.document, test.expected, errs)
+		}
+	}
+}
+
+func TestJSONArrayStreaming(t *testing.T) {
+	reader, writer := io.Pipe()
+	first := make(chan any)
+	go func() {
+		defer writer.Close()
+		fmt.Fprint(writer, "[0,")
+		// wait for the first element to be consumed before writing the rest
+		<-first
+		for i := 1; i < 999; i++ {
+			fmt.Fprintf(writer, "%d,", i)
+		}
+		fmt.Fprint(writer, "999]")
+	}()
+	generate, err := utilities.MakeJSONArrayGenerator[int](reader, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	utilities.ProcessBatch(
+		1,
+		0,
+		0,
+		generate,
+		func(input utilities.JSONArrayTransformerParameters[int]) int { return input.Value },
+		func(n int) {
+			if count == 0 {
+				close(first)
+			}
+			count++
+		},
+	)
+	if count != 1000 {
+		t.Errorf("expected 1000, got %d", count)
+	}
+}
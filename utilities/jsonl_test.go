@@ -0,0 +1,88 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"parasaurolophus/utilities"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const eventsJSONL = `{"id":"a","on":{"on":true},"dimming":{"brightness":50}}
+{"id":"b","on":{"on":false}}
+
+{"id":"c",
+{"id":"d","on":{"on":true}}
+`
+
+func TestJSONLMaps(t *testing.T) {
+	errs := []error{}
+	errorHandler := func(err error) {
+		errs = append(errs, err)
+	}
+	generate, err := utilities.MakeJSONLGenerator[map[string]any](strings.NewReader(eventsJSONL), errorHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform := func(input utilities.JSONLTransformerParameters[map[string]any]) map[string]any {
+		on, err := utilities.GetJSONPath[bool](input.Value, "on", "on")
+		if err != nil {
+			errorHandler(err)
+		}
+		id, _ := utilities.GetJSONPath[string](input.Value, "id")
+		return map[string]any{"id": id, "on": on, "line": input.Line}
+	}
+	buffer := bytes.Buffer{}
+	consume := utilities.MakeJSONLConsumer[map[string]any](&buffer, errorHandler)
+	utilities.ProcessBatch(1, 0, 0, generate, transform, consume)
+	var jsonlError *utilities.JSONLError
+	if len(errs) != 1 || !errors.As(errs[0], &jsonlError) || jsonlError.Line != 4 {
+		t.Errorf("expected an error for line 4, got %v", errs)
+	}
+	expected := `{"id":"a","line":1,"on":true}
+{"id":"b","line":2,"on":false}
+{"id":"d","line":5,"on":true}
+`
+	if buffer.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buffer.String())
+	}
+}
+
+func TestJSONLStructs(t *testing.T) {
+	type light struct {
+		Id string `json:"id"`
+		On struct {
+			On bool `json:"on"`
+		} `json:"on"`
+	}
+	errs := []error{}
+	errorHandler := func(err error) {
+		errs = append(errs, err)
+	}
+	generate, err := utilities.MakeJSONLGenerator[light](strings.NewReader(eventsJSONL), errorHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	transform := func(input utilities.JSONLTransformerParameters[light]) light {
+		return input.Value
+	}
+	consume := func(l light) {
+		if l.On.On {
+			ids = append(ids, l.Id)
+		}
+	}
+	utilities.ProcessBatch(2, 0, 0, generate, transform, consume)
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"a", "d"}) {
+		t.Errorf("unexpected %v", ids)
+	}
+	var syntaxError *json.SyntaxError
+	if len(errs) != 1 || !errors.As(errs[0], &syntaxError) {
+		t.Errorf("expected a syntax error, got %v", errs)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+
+	// Limits the rate at which, and the number of goroutines by which, some
+	// operation is performed. Goroutines exceeding the limits wait rather than
+	// fail. A single Limiter is meant to be shared by all of the workers in a
+	// pool, e.g. all of those calling a particular device's API.
+	//
+	// See Limit, LimitContext, NewLimiter
+	Limiter struct {
+		lock   sync.Mutex
+		every  time.Duration
+		burst  float64
+		tokens float64
+		last   time.Time
+		slots  chan any
+	}
+)
+
+// Return a Limiter which allows an operation to be started at most once per
+// the given interval on average, using a token bucket holding up to burst
+// tokens so that up to that many may start at once after a quiet period, and
+// allows at most maxInFlight operations to be in progress at any given time.
+// The rate is not limited if every is zero and the number of operations in
+// progress is not limited if maxInFlight is zero.
+//
+// See Limiter
+func NewLimiter(every time.Duration, burst int, maxInFlight int) *Limiter {
+
+	limiter := &Limiter{
+		every:  every,
+		burst:  float64(max(burst, 1)),
+		tokens: float64(max(burst, 1)),
+		last:   time.Now(),
+	}
+
+	if maxInFlight > 0 {
+		limiter.slots = make(chan any, maxInFlight)
+	}
+
+	return limiter
+}
+
+// Wait until an operation may be started without exceeding the limiter's
+// limits, or until ctx is done, in which case ctx.Err() is returned. Each
+// successful call must be followed by a call to Release once the operation
+// is finished.
+func (limiter *Limiter) Acquire(ctx context.Context) error {
+
+	if limiter.slots != nil {
+		select {
+		case limiter.slots <- nil:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := limiter.reserve(ctx); err != nil {
+		limiter.Release()
+		return err
+	}
+
+	return nil
+}
+
+// Signal that an operation started after a call to Acquire has finished.
+func (limiter *Limiter) Release() {
+
+	if limiter.slots != nil {
+		<-limiter.slots
+	}
+}
+
+// Take a token from the bucket, waiting for one to become available if
+// necessary. Tokens are taken in the order requested by letting the bucket
+// go negative, each waiter sleeping for as long as it will take to refill.
+func (limiter *Limiter) reserve(ctx context.Context) error {
+
+	if limiter.every <= 0 {
+		return ctx.Err()
+	}
+
+	limiter.lock.Lock()
+	now := time.Now()
+	limiter.tokens = min(limiter.burst, limiter.tokens+float64(now.Sub(limiter.last))/float64(limiter.every))
+	limiter.last = now
+	limiter.tokens--
+	delay := time.Duration(-limiter.tokens * float64(limiter.every))
+	limiter.lock.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+
+	case <-timer.C:
+		return nil
+
+	case <-ctx.Done():
+		// return the token that will not be used
+		limiter.lock.Lock()
+		limiter.tokens++
+		limiter.lock.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Return a handler which invokes the given one only after acquiring the given
+// limiter, releasing it afterwards. For example, to ensure that a device's API
+// is invoked no more than ten times per second by all of the workers in a
+// pool put together:
+//
+//	{
+//	  limiter := NewLimiter(time.Second/10, 1, 0)
+//	  values, await := StartWorkers(numWorkers, bufferSize, Limit(limiter, handler))
+//	  defer CloseAllAndWait(values, await)
+//	  for i, value := range data {
+//	    values[i%numWorkers] <- value
+//	  }
+//	}
+//
+// See LimitContext, Limiter, StartWorker, StartWorkers
+func Limit[V any](
+
+	limiter *Limiter,
+	handler func(V),
+
+) func(V) {
+
+	return func(value V) {
+		_ = limiter.Acquire(context.Background())
+		defer limiter.Release()
+		handler(value)
+	}
+}
+
+// Like Limit, but for handlers that are passed a context. Values are
+// discarded without invoking the handler if the context is done while waiting
+// for the limiter.
+//
+// See Limit, Limiter, StartWorkerContext, StartWorkersContext
+func LimitContext[V any](
+
+	limiter *Limiter,
+	handler func(context.Context, V),
+
+) func(context.Context, V) {
+
+	return func(ctx context.Context, value V) {
+		if limiter.Acquire(ctx) != nil {
+			return
+		}
+		defer limiter.Release()
+		handler(ctx, value)
+	}
+}
+
+// Return a function which invokes the given transform function only after
+// acquiring the given limiter, releasing it afterwards.
+func limitTransform[Input any, Output any](
+
+	limiter *Limiter,
+	transform func(context.Context, Input) (Output, error),
+
+) func(context.Context, Input) (Output, error) {
+
+	return func(ctx context.Context, input Input) (output Output, err error) {
+		if err = limiter.Acquire(ctx); err != nil {
+			return
+		}
+		defer limiter.Release()
+		return transform(ctx, input)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"errors"
+	"parasaurolophus/utilities"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterRate(t *testing.T) {
+	limiter := utilities.NewLimiter(time.Millisecond*10, 2, 0)
+	count := atomic.Int32{}
+	handler := func(int) {
+		count.Add(1)
+	}
+	start := time.Now()
+	func() {
+		values, await := utilities.StartWorkers(4, 0, utilities.Limit(limiter, handler))
+		defer utilities.CloseAllAndWait(values, await)
+		for i := range 6 {
+			values[i%len(values)] <- i
+		}
+	}()
+	// 2 immediately from the burst, then 4 more at 10ms intervals
+	if elapsed := time.Since(start); elapsed < time.Millisecond*40 {
+		t.Errorf("expected at least 40ms, got %s", elapsed)
+	}
+	if count.Load() != 6 {
+		t.Errorf("expected 6, got %d", count.Load())
+	}
+}
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	limiter := utilities.NewLimiter(0, 0, 2)
+	inFlight := atomic.Int32{}
+	peak := atomic.Int32{}
+	handler := func(_ context.Context, n int) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if current <= p || peak.CompareAndSwap(p, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 2)
+	}
+	func() {
+		values, await := utilities.StartSharedWorkersContext(context.Background(), 5, 0, utilities.LimitContext(limiter, handler))
+		defer utilities.CloseAllAndWait([]chan<- int{values}, await)
+		for i := range 20 {
+			values <- i
+		}
+	}()
+	if peak.Load() != 2 {
+		t.Errorf("expected at most 2 in flight, got %d", peak.Load())
+	}
+}
+
+func TestLimiterCanceled(t *testing.T) {
+	limiter := utilities.NewLimiter(time.Hour, 1, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := limiter.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := limiter.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	limiter.Release()
+}
+
+func TestProcessBatchWithErrorsLimiter(t *testing.T) {
+	lock := sync.Mutex{}
+	times := []time.Time{}
+	options := utilities.BatchOptions{
+		NumTransformers: 4,
+		Limiter:         utilities.NewLimiter(time.Millisecond*5, 1, 0),
+	}
+	generate := func(_ context.Context, transformers []chan<- int) error {
+		for i := range 5 {
+			transformers[i%len(transformers)] <- i
+		}
+		return nil
+	}
+	transform := func(_ context.Context, input int) (int, error) {
+		defer lock.Unlock()
+		lock.Lock()
+		times = append(times, time.Now())
+		return input, nil
+	}
+	consume := func(context.Context, int) error {
+		return nil
+	}
+	err := utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := times[4].Sub(times[0]); elapsed < time.Millisecond*19 {
+		t.Errorf("expected transforms to be spread over at least 20ms, got %s", elapsed)
+	}
+}
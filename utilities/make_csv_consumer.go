@@ -3,6 +3,7 @@
 package utilities
 
 import (
+	"context"
 	"encoding/csv"
 )
 
@@ -22,9 +23,10 @@ type (
 // Return a function for use as the consume parameter to ProcessBatch. The
 // returned function will write each received row to the given CSV file. Any
 // errors encountered along the way will be passed to the given errorHandler
-// function.
+// function. No header row is written; use MakeCSVConsumerWithOptions for that.
 //
-// See ProcessBatch, MakeCSVGenerator
+// See ProcessBatch, MakeCSVConsumerWithErrors, MakeCSVConsumerWithOptions,
+// MakeCSVGenerator
 func MakeCSVConsumer(
 
 	writer *csv.Writer,
@@ -37,13 +39,10 @@ func MakeCSVConsumer(
 
 ) {
 
+	c := MakeCSVConsumerWithErrors(writer, headers)
+
 	consumer = func(parameters CSVConsumerParamters) {
-		columns := make([]string, len(parameters.Output))
-		for i, h := range headers {
-			columns[i] = parameters.Output[h]
-		}
-		e := writer.Write(columns)
-		if e != nil {
+		if e := c(context.Background(), parameters); e != nil {
 			errorHandler(e)
 		}
 	}
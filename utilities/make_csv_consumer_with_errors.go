@@ -0,0 +1,33 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"encoding/csv"
+)
+
+// Like MakeCSVConsumer, but return a function for use as the consume parameter
+// to ProcessBatchWithErrors. Errors writing a row are returned rather than
+// passed to an error handler.
+//
+// The value of each header in Output is written in the order of the given
+// headers, with missing values written as empty columns, so headers can be
+// used to select and reorder the columns to be written.
+//
+// See MakeCSVConsumer, MakeCSVConsumerWithOptions, MakeCSVGeneratorWithErrors,
+// ProcessBatchWithErrors
+func MakeCSVConsumerWithErrors(
+
+	writer *csv.Writer,
+	headers []string,
+
+) (
+
+	consumer func(context.Context, CSVConsumerParamters) error,
+
+) {
+
+	consumer, _ = MakeCSVConsumerWithOptions(writer, headers, CSVConsumerOptions{})
+	return
+}
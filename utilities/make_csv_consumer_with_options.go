@@ -0,0 +1,154 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type (
+
+	// What a consumer created by MakeCSVConsumerWithOptions does with a row
+	// whose Output has keys that are not among its headers, or lacks some of
+	// them.
+	//
+	// See CSVConsumerOptions
+	KeyPolicy int
+
+	// Parameters for MakeCSVConsumerWithOptions.
+	//
+	// See MakeCSVConsumerWithOptions
+	CSVConsumerOptions struct {
+
+		// Whether to write a row of column headers before any other rows.
+		WriteHeaders bool
+
+		// Names to write in the header row in place of the corresponding
+		// Output keys. Keys not in Rename are written as they are.
+		Rename map[string]string
+
+		// What to do with rows whose Output has keys not among the headers.
+		ExtraKeys KeyPolicy
+
+		// What to do with rows whose Output lacks some of the headers.
+		MissingKeys KeyPolicy
+	}
+
+	// Error for a row whose Output keys do not match a consumer's headers.
+	//
+	// See KeyPolicy
+	CSVKeyError struct {
+		Row     int
+		Extra   []string
+		Missing []string
+	}
+)
+
+const (
+
+	// Write the row, ignoring extra keys and writing missing ones as empty
+	// columns.
+	IgnoreKeys = KeyPolicy(iota)
+
+	// Write the row as for IgnoreKeys, but return a *CSVKeyError.
+	ReportKeys
+
+	// Return a *CSVKeyError without writing the row.
+	RejectKeys
+)
+
+func (err *CSVKeyError) Error() string {
+
+	var problems []string
+
+	if len(err.Extra) > 0 {
+		problems = append(problems, fmt.Sprintf("extra keys %v", err.Extra))
+	}
+
+	if len(err.Missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing keys %v", err.Missing))
+	}
+
+	return fmt.Sprintf("row %d: %s", err.Row, strings.Join(problems, ", "))
+}
+
+// Like MakeCSVConsumerWithErrors, but with control over the header row and
+// over rows whose Output keys do not match the given headers. The headers
+// select the Output keys to be written and the order in which they are
+// written. For example, to write just the "id" and "total" keys, with a
+// header row that names them "ID" and "Total":
+//
+//	options := CSVConsumerOptions{
+//	  WriteHeaders: true,
+//	  Rename:       map[string]string{"id": "ID", "total": "Total"},
+//	  MissingKeys:  RejectKeys,
+//	}
+//	consume, err := MakeCSVConsumerWithOptions(writer, []string{"id", "total"}, options)
+//
+// The header row, if any, is written immediately, in which case any error
+// writing it is returned.
+//
+// See CSVConsumerOptions, CSVKeyError, MakeCSVConsumerWithErrors
+func MakeCSVConsumerWithOptions(
+
+	writer *csv.Writer,
+	headers []string,
+	options CSVConsumerOptions,
+
+) (
+
+	consumer func(context.Context, CSVConsumerParamters) error,
+	err error,
+
+) {
+
+	if options.WriteHeaders {
+		names := make([]string, len(headers))
+		for i, h := range headers {
+			if name, ok := options.Rename[h]; ok {
+				names[i] = name
+			} else {
+				names[i] = h
+			}
+		}
+		if err = writer.Write(names); err != nil {
+			return
+		}
+	}
+
+	consumer = func(_ context.Context, parameters CSVConsumerParamters) error {
+		keyError := &CSVKeyError{Row: parameters.Row}
+		columns := make([]string, len(headers))
+		for i, h := range headers {
+			value, ok := parameters.Output[h]
+			if !ok && options.MissingKeys != IgnoreKeys {
+				keyError.Missing = append(keyError.Missing, h)
+			}
+			columns[i] = value
+		}
+		if options.ExtraKeys != IgnoreKeys {
+			for key := range parameters.Output {
+				if !slices.Contains(headers, key) {
+					keyError.Extra = append(keyError.Extra, key)
+				}
+			}
+			slices.Sort(keyError.Extra)
+		}
+		if (len(keyError.Missing) > 0 && options.MissingKeys == RejectKeys) ||
+			(len(keyError.Extra) > 0 && options.ExtraKeys == RejectKeys) {
+			return keyError
+		}
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+		if len(keyError.Missing) > 0 || len(keyError.Extra) > 0 {
+			return keyError
+		}
+		return nil
+	}
+	return
+}
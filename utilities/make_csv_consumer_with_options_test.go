@@ -0,0 +1,67 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"parasaurolophus/utilities"
+	"slices"
+	"testing"
+)
+
+func consumerParameters(row int, output map[string]string) utilities.CSVConsumerParamters {
+	parameters := utilities.CSVConsumerParamters{Output: output}
+	parameters.Row = row
+	return parameters
+}
+
+func TestMakeCSVConsumerWithErrorsColumns(t *testing.T) {
+	buffer := bytes.Buffer{}
+	writer := csv.NewWriter(&buffer)
+	consume := utilities.MakeCSVConsumerWithErrors(writer, []string{"b", "a", "c"})
+	// fewer and more Output keys than headers
+	if err := consume(context.Background(), consumerParameters(1, map[string]string{"a": "1"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := consume(context.Background(), consumerParameters(2, map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"})); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+	if expected := ",1,\n2,1,3\n"; buffer.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buffer.String())
+	}
+}
+
+func TestMakeCSVConsumerWithOptions(t *testing.T) {
+	buffer := bytes.Buffer{}
+	writer := csv.NewWriter(&buffer)
+	options := utilities.CSVConsumerOptions{
+		WriteHeaders: true,
+		Rename:       map[string]string{"total": "Total"},
+		ExtraKeys:    utilities.ReportKeys,
+		MissingKeys:  utilities.RejectKeys,
+	}
+	consume, err := utilities.MakeCSVConsumerWithOptions(writer, []string{"total", "id"}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := consume(context.Background(), consumerParameters(1, map[string]string{"id": "a", "total": "1"})); err != nil {
+		t.Error(err)
+	}
+	err = consume(context.Background(), consumerParameters(2, map[string]string{"id": "b", "total": "2", "note": "x", "debug": "y"}))
+	var keyError *utilities.CSVKeyError
+	if !errors.As(err, &keyError) || keyError.Row != 2 || !slices.Equal(keyError.Extra, []string{"debug", "note"}) || len(keyError.Missing) != 0 {
+		t.Errorf("unexpected error %v", err)
+	}
+	err = consume(context.Background(), consumerParameters(3, map[string]string{"id": "c"}))
+	if !errors.As(err, &keyError) || !slices.Equal(keyError.Missing, []string{"total"}) {
+		t.Errorf("unexpected error %v", err)
+	}
+	writer.Flush()
+	if expected := "Total,id\n1,a\n2,b\n"; buffer.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buffer.String())
+	}
+}
@@ -3,8 +3,8 @@
 package utilities
 
 import (
+	"context"
 	"encoding/csv"
-	"io"
 )
 
 type (
@@ -16,9 +16,38 @@ type (
 	CSVTransformerParameters struct {
 		Row   int
 		Input map[string]string
+
+		// Line of the CSV file on which the row starts and the row's fields
+		// exactly as read, for reporting rows that fail.
+		Line    int
+		Columns []string
+
+		// Values of the columns declared by a CSVSchema, converted to the
+		// declared types, when the row was validated against one.
+		Values map[string]any
+	}
+
+	// Error reading a row of a CSV file, along with as much of the row as
+	// could be read. Columns is nil unless the row was read but had the wrong
+	// number of fields.
+	//
+	// See CSVRejects, MakeCSVGeneratorWithErrors
+	CSVReadError struct {
+		CSVTransformerParameters
+		Err error
 	}
 )
 
+func (err *CSVReadError) Error() string {
+
+	return err.Err.Error()
+}
+
+func (err *CSVReadError) Unwrap() error {
+
+	return err.Err
+}
+
 // Return a function for use as the generate parameter to ProcessBatch. The
 // returned function reads the rows of the given CSV file, sending each to the
 // batch's transformers channels in round-robin fashion. Any errors encountered
@@ -26,7 +55,7 @@ type (
 // the column headers and starting row number are passed in here so as to
 // support CSV's without a headers row.
 //
-// See ProcessBatch, MakeCSVConsumer
+// See ProcessBatch, MakeCSVConsumer, MakeCSVGeneratorContext
 func MakeCSVGenerator(
 
 	reader *csv.Reader,
@@ -41,27 +70,14 @@ func MakeCSVGenerator(
 
 ) {
 
+	var g func(context.Context, []chan<- CSVTransformerParameters)
+
+	if g, err = MakeCSVGeneratorContext(reader, headers, startRow, errorHandler); err != nil {
+		return
+	}
+
 	generator = func(transformers []chan<- CSVTransformerParameters) {
-		row := startRow
-		n := len(transformers)
-		for {
-			columns, e := reader.Read()
-			if e != nil {
-				if e != io.EOF {
-					errorHandler(e)
-				}
-				break
-			}
-			parameters := CSVTransformerParameters{
-				Row:   row,
-				Input: map[string]string{},
-			}
-			for i, h := range headers {
-				parameters.Input[h] = columns[i]
-			}
-			transformers[(row-startRow)%n] <- parameters
-			row++
-		}
+		g(context.Background(), transformers)
 	}
 	return
 }
@@ -0,0 +1,42 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"encoding/csv"
+)
+
+// Like MakeCSVGenerator, but return a function for use as the generate
+// parameter to ProcessBatchContext. The returned function stops reading rows
+// from the given CSV file once its context is done.
+//
+// See MakeCSVConsumer, MakeCSVGenerator, MakeCSVGeneratorWithErrors,
+// ProcessBatchContext
+func MakeCSVGeneratorContext(
+
+	reader *csv.Reader,
+	headers []string,
+	startRow int,
+	errorHandler func(error),
+
+) (
+
+	generator func(context.Context, []chan<- CSVTransformerParameters),
+	err error,
+
+) {
+
+	var g func(context.Context, []chan<- CSVTransformerParameters) error
+
+	if g, err = MakeCSVGeneratorWithErrors(reader, headers, startRow); err != nil {
+		return
+	}
+
+	generator = func(ctx context.Context, transformers []chan<- CSVTransformerParameters) {
+		if e := g(ctx, transformers); e != nil {
+			errorHandler(e)
+		}
+	}
+	return
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"encoding/csv"
+)
+
+// Like MakeCSVGeneratorContext, but return a function for use as the generate
+// parameter to ProcessBatchWithErrors. Rather than being passed to an error
+// handler, an error reading a row is returned as an ItemError whose Index is
+// the offset of that row from startRow, wrapping a *CSVReadError.
+//
+// See MakeCSVConsumerWithErrors, MakeCSVGeneratorContext, MakeCSVSeq,
+// MakePartitionedCSVGenerator, ProcessBatchWithErrors
+func MakeCSVGeneratorWithErrors(
+
+	reader *csv.Reader,
+	headers []string,
+	startRow int,
+
+) (
+
+	generator func(context.Context, []chan<- CSVTransformerParameters) error,
+	err error,
+
+) {
+
+	generator = makeCSVGenerator(reader, headers, startRow, nil)
+	return
+}
+
+// Return a CSV generator which sends rows to its transformers in round-robin
+// fashion if key is nil, otherwise according to a Partitioner using key.
+func makeCSVGenerator(
+
+	reader *csv.Reader,
+	headers []string,
+	startRow int,
+	key func(CSVTransformerParameters) string,
+
+) func(context.Context, []chan<- CSVTransformerParameters) error {
+
+	return func(ctx context.Context, transformers []chan<- CSVTransformerParameters) error {
+		n := len(transformers)
+		var partitioner *Partitioner[CSVTransformerParameters]
+		if key != nil {
+			partitioner = NewPartitioner(n, key)
+		}
+		for parameters, err := range csvRows(reader, headers, startRow) {
+			if ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if partitioner != nil {
+				partitioner.Send(transformers, parameters)
+			} else {
+				transformers[(parameters.Row-startRow)%n] <- parameters
+			}
+		}
+		return nil
+	}
+}
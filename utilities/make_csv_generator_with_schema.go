@@ -0,0 +1,79 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"encoding/csv"
+)
+
+// Like MakeCSVGeneratorWithErrors, but validate each row against the given
+// schema before sending it to a transformer, setting its Values to the
+// converted values of the schema's columns. An invalid row is not sent to any
+// transformer. Instead, it is passed to errorHandler as an ItemError whose
+// Index is the offset of the row from startRow, wrapping a *CSVReadError which
+// wraps the row's *CSVValidationError values, and generation continues. If
+// errorHandler is nil, the error is returned, ending generation, as for an
+// error reading a row. For example:
+//
+//	schema := CSVSchema{
+//	  {Name: "label", Required: true, Pattern: regexp.MustCompile(`^[a-z]+$`)},
+//	  {Name: "number", Type: IntColumn, Required: true},
+//	}
+//	generate, err := MakeCSVGeneratorWithSchema(reader, headers, 1, schema, rejects.HandleError)
+//
+// Since invalid rows are skipped, the index of an ItemError reported by the
+// batch for a later row is its position among the rows that were sent rather
+// than its offset from startRow. Use the row's Row instead. When using a
+// CSVCheckpointer, wrap errorHandler using its HandleError method so that
+// invalid rows are recorded as completed. Returns an error if any of the
+// schema's columns are not in headers.
+//
+// See CSVCheckpointer, CSVRejects, CSVSchema, MakeCSVGeneratorWithErrors
+func MakeCSVGeneratorWithSchema(
+
+	reader *csv.Reader,
+	headers []string,
+	startRow int,
+	schema CSVSchema,
+	errorHandler func(error),
+
+) (
+
+	generator func(context.Context, []chan<- CSVTransformerParameters) error,
+	err error,
+
+) {
+
+	if err = schema.Check(headers); err != nil {
+		return
+	}
+
+	generator = func(ctx context.Context, transformers []chan<- CSVTransformerParameters) error {
+		n := len(transformers)
+		sent := 0
+		for parameters, err := range csvRows(reader, headers, startRow) {
+			if ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if parameters.Values, err = schema.Validate(parameters.Input); err != nil {
+				err = &ItemError{
+					Index: parameters.Row - startRow,
+					Err:   &CSVReadError{CSVTransformerParameters: parameters, Err: err},
+				}
+				if errorHandler == nil {
+					return err
+				}
+				errorHandler(err)
+				continue
+			}
+			transformers[sent%n] <- parameters
+			sent++
+		}
+		return nil
+	}
+	return
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"iter"
+	"slices"
+)
+
+// Return a sequence which lazily reads the rows of the given CSV file, as
+// MakeCSVGeneratorWithErrors does, yielding each one as it is read. An error
+// reading a row is yielded as an ItemError whose Index is the offset of that
+// row from startRow, wrapping a *CSVReadError, after which the sequence ends.
+// For example:
+//
+//	rows, err := MakeCSVSeq(reader, headers, 1)
+//	...
+//	outputs, err := ProcessSeq2(ctx, options, rows, transform)
+//
+// See MakeCSVGeneratorWithErrors, ProcessSeq2
+func MakeCSVSeq(
+
+	reader *csv.Reader,
+	headers []string,
+	startRow int,
+
+) (
+
+	rows iter.Seq2[CSVTransformerParameters, error],
+	err error,
+
+) {
+
+	rows = csvRows(reader, headers, startRow)
+	return
+}
+
+// Return a sequence of the rows read from the given CSV file.
+func csvRows(reader *csv.Reader, headers []string, startRow int) iter.Seq2[CSVTransformerParameters, error] {
+
+	return func(yield func(CSVTransformerParameters, error) bool) {
+		for row := startRow; ; row++ {
+			columns, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if reader.ReuseRecord {
+				columns = slices.Clone(columns)
+			}
+			parameters := CSVTransformerParameters{
+				Row:     row,
+				Columns: columns,
+			}
+			if len(columns) > 0 {
+				parameters.Line, _ = reader.FieldPos(0)
+			}
+			if err != nil {
+				var parseError *csv.ParseError
+				if errors.As(err, &parseError) {
+					parameters.Line = parseError.StartLine
+				}
+				yield(parameters, &ItemError{
+					Index: row - startRow,
+					Err:   &CSVReadError{CSVTransformerParameters: parameters, Err: err},
+				})
+				return
+			}
+			parameters.Input = map[string]string{}
+			for i, h := range headers {
+				parameters.Input[h] = columns[i]
+			}
+			if !yield(parameters, nil) {
+				return
+			}
+		}
+	}
+}
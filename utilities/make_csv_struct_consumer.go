@@ -0,0 +1,41 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"encoding/csv"
+	"reflect"
+)
+
+// Like MakeCSVConsumerWithErrors, but encode each value of the struct type T
+// as a row with the given headers. Fields are bound to columns, and their
+// values formatted, as for MakeCSVStructGenerator, with nil pointers written
+// as empty columns. Columns without a corresponding field are left empty.
+// Returns an error if T is not a struct or any of its fields' columns are not
+// in headers.
+//
+// See MakeCSVConsumerWithErrors, MakeCSVStructGenerator
+func MakeCSVStructConsumer[T any](
+
+	writer *csv.Writer,
+	headers []string,
+
+) (
+
+	consumer func(context.Context, T) error,
+	err error,
+
+) {
+
+	var binding *csvBinding
+
+	if binding, err = newCSVBinding(reflect.TypeFor[T](), headers); err != nil {
+		return
+	}
+
+	consumer = func(_ context.Context, value T) error {
+		return writer.Write(binding.encode(reflect.ValueOf(value)))
+	}
+	return
+}
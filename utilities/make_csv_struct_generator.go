@@ -0,0 +1,87 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"encoding/csv"
+	"reflect"
+)
+
+// Like MakeCSVGeneratorWithErrors, but decode each row into a new value of the
+// struct type T before sending it to a transformer. Each exported field of T
+// is set from the column named by its csv tag, or by the field's name if it
+// has none, while fields tagged "-" are ignored. For example:
+//
+//	type Reading struct {
+//	  Device   string        `csv:"device_id"`
+//	  Value    float64       `csv:"value"`
+//	  Online   bool          `csv:"online"`
+//	  Interval time.Duration `csv:"interval"`
+//	  When     time.Time     `csv:"when,layout=2006-01-02 15:04"`
+//	  Offset   *int          `csv:"offset"`
+//	}
+//	generate, err := MakeCSVStructGenerator[Reading](reader, headers, 1, rejects.HandleError)
+//
+// Numbers are parsed using ParseNumber, bools using strconv.ParseBool,
+// durations using time.ParseDuration and times using time.Parse with the
+// layout given in the tag, or time.RFC3339 by default. A pointer field is nil
+// if its column is empty, otherwise it points to the parsed value, so pointers
+// can be used for optional fields.
+//
+// A row that cannot be decoded is not sent to any transformer. Instead, it is
+// passed to errorHandler, or returned if errorHandler is nil, as for
+// MakeCSVGeneratorWithSchema, including when using CSVCheckpointer.HandleError
+// to record it as completed. Returns an error if T is not a struct or any of
+// its fields' columns are not in headers.
+//
+// See MakeCSVGeneratorWithSchema, MakeCSVStructConsumer
+func MakeCSVStructGenerator[T any](
+
+	reader *csv.Reader,
+	headers []string,
+	startRow int,
+	errorHandler func(error),
+
+) (
+
+	generator func(context.Context, []chan<- T) error,
+	err error,
+
+) {
+
+	var binding *csvBinding
+
+	if binding, err = newCSVBinding(reflect.TypeFor[T](), headers); err != nil {
+		return
+	}
+
+	generator = func(ctx context.Context, transformers []chan<- T) error {
+		n := len(transformers)
+		sent := 0
+		for parameters, err := range csvRows(reader, headers, startRow) {
+			if ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			var value T
+			if err = binding.decode(parameters.Columns, reflect.ValueOf(&value).Elem()); err != nil {
+				err = &ItemError{
+					Index: parameters.Row - startRow,
+					Err:   &CSVReadError{CSVTransformerParameters: parameters, Err: err},
+				}
+				if errorHandler == nil {
+					return err
+				}
+				errorHandler(err)
+				continue
+			}
+			transformers[sent%n] <- value
+			sent++
+		}
+		return nil
+	}
+	return
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type (
+
+	// Data sent to transformers channel by functions created using
+	// MakeJSONArrayGenerator. Index is the position of Value in the array.
+	//
+	// See MakeJSONArrayGenerator
+	JSONArrayTransformerParameters[T any] struct {
+		Index int
+		Value T
+	}
+)
+
+// Return a function for use as the generate parameter to ProcessBatch. The
+// returned function streams the elements of a JSON array from the given
+// reader, decoding each into a value of type T and sending it to the batch's
+// transformers channels in round-robin fashion. If no path is given, the
+// document must be an array. Otherwise, the array is the value found by
+// following the given keys through nested objects, as for GetJSONPath. For
+// example, to process the "data" of a hue API response:
+//
+//	generate, err := MakeJSONArrayGenerator[hue.Item](reader, errorHandler, "data")
+//
+// Only one element is decoded at a time and the values of keys not on the
+// path are skipped token by token, so memory use does not depend on the size
+// of the document. Reading stops at the end of the array.
+//
+// An element that cannot be decoded into T is passed to the given errorHandler
+// as an ItemError whose Index is its position in the array, and generation
+// continues. Any other error, such as malformed JSON or a missing path, is
+// passed to errorHandler and ends generation.
+//
+// See GetJSONPath, JSONArrayTransformerParameters, MakeJSONLGenerator,
+// ProcessBatch
+func MakeJSONArrayGenerator[T any](
+
+	reader io.Reader,
+	errorHandler func(error),
+	path ...string,
+
+) (
+
+	generator func([]chan<- JSONArrayTransformerParameters[T]),
+	err error,
+
+) {
+
+	generator = func(transformers []chan<- JSONArrayTransformerParameters[T]) {
+		decoder := json.NewDecoder(reader)
+		if err := findJSONPath(decoder, path); err != nil {
+			errorHandler(err)
+			return
+		}
+		if err := expectDelim(decoder, '['); err != nil {
+			if len(path) > 0 {
+				err = fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+			}
+			errorHandler(err)
+			return
+		}
+		n := len(transformers)
+		sent := 0
+		for index := 0; decoder.More(); index++ {
+			parameters := JSONArrayTransformerParameters[T]{Index: index}
+			if err := decoder.Decode(&parameters.Value); err != nil {
+				var typeError *json.UnmarshalTypeError
+				if !errors.As(err, &typeError) {
+					errorHandler(err)
+					return
+				}
+				// the decoder has consumed the whole element
+				errorHandler(&ItemError{Index: index, Err: err})
+				continue
+			}
+			transformers[sent%n] <- parameters
+			sent++
+		}
+		if err := expectDelim(decoder, ']'); err != nil {
+			errorHandler(err)
+		}
+	}
+	return
+}
+
+// Advance the given decoder to the value found by following the given keys
+// through nested objects.
+func findJSONPath(decoder *json.Decoder, path []string) error {
+
+	for depth, key := range path {
+
+		if err := expectDelim(decoder, '{'); err != nil {
+			if depth > 0 {
+				err = fmt.Errorf("%s: %w", strings.Join(path[:depth], "."), err)
+			}
+			return err
+		}
+
+		for {
+			if !decoder.More() {
+				return fmt.Errorf(`no value found for "%s"`, strings.Join(path[:depth+1], "."))
+			}
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			if token == key {
+				break
+			}
+			if err = skipJSONValue(decoder); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Read the next token from the given decoder, returning an error unless it is
+// the given delimiter.
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+
+	token, err := decoder.Token()
+
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return fmt.Errorf("expected %s, found %v", delim, token)
+	}
+
+	return nil
+}
+
+// Read the tokens making up the next value from the given decoder without
+// retaining them.
+func skipJSONValue(decoder *json.Decoder) error {
+
+	depth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
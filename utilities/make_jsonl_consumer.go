@@ -0,0 +1,35 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Return a function for use as the consume parameter to ProcessBatch. The
+// returned function will write each received value to the given writer as a
+// single line of JSON. Any errors encountered along the way will be passed to
+// the given errorHandler function.
+//
+// See MakeCSVConsumer, MakeJSONLGenerator, ProcessBatch
+func MakeJSONLConsumer[T any](
+
+	writer io.Writer,
+	errorHandler func(error),
+
+) (
+
+	consumer func(T),
+
+) {
+
+	encoder := json.NewEncoder(writer)
+
+	consumer = func(value T) {
+		if err := encoder.Encode(value); err != nil {
+			errorHandler(err)
+		}
+	}
+	return
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type (
+
+	// Data sent to transformers channel by functions created using
+	// MakeJSONLGenerator. Line is the line of the input on which Value was
+	// found.
+	//
+	// See MakeJSONLGenerator
+	JSONLTransformerParameters[T any] struct {
+		Line  int
+		Value T
+	}
+
+	// Error decoding a line of JSON Lines input.
+	//
+	// See MakeJSONLGenerator
+	JSONLError struct {
+		Line int
+		Err  error
+	}
+)
+
+func (err *JSONLError) Error() string {
+
+	return fmt.Sprintf("line %d: %s", err.Line, err.Err.Error())
+}
+
+func (err *JSONLError) Unwrap() error {
+
+	return err.Err
+}
+
+// Return a function for use as the generate parameter to ProcessBatch. The
+// returned function reads the given newline-delimited JSON, decoding each line
+// into a value of type T and sending it to the batch's transformers channels
+// in round-robin fashion. Use map[string]any for T to decode records for use
+// with GetJSONPath, or a struct type to decode them as for json.Unmarshal.
+// Blank lines are skipped. A line that cannot be decoded is passed to the
+// given errorHandler as a *JSONLError and reading continues with the next
+// line, while an error reading the input is passed to errorHandler and ends
+// generation.
+//
+// See JSONLTransformerParameters, MakeCSVGenerator, MakeJSONLConsumer,
+// ProcessBatch
+func MakeJSONLGenerator[T any](
+
+	reader io.Reader,
+	errorHandler func(error),
+
+) (
+
+	generator func([]chan<- JSONLTransformerParameters[T]),
+	err error,
+
+) {
+
+	generator = func(transformers []chan<- JSONLTransformerParameters[T]) {
+		r := bufio.NewReader(reader)
+		n := len(transformers)
+		sent := 0
+		for line := 1; ; line++ {
+			b, e := r.ReadBytes('\n')
+			if e != nil && !errors.Is(e, io.EOF) {
+				errorHandler(e)
+				return
+			}
+			if b = bytes.TrimSpace(b); len(b) > 0 {
+				parameters := JSONLTransformerParameters[T]{Line: line}
+				if err := json.Unmarshal(b, &parameters.Value); err != nil {
+					errorHandler(&JSONLError{Line: line, Err: err})
+				} else {
+					transformers[sent%n] <- parameters
+					sent++
+				}
+			}
+			if e != nil {
+				return
+			}
+		}
+	}
+	return
+}
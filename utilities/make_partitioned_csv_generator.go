@@ -0,0 +1,45 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"encoding/csv"
+)
+
+// Like MakeCSVGeneratorWithErrors, but rather than sending rows to the batch's
+// transformers channels in round-robin fashion, send all of the rows with the
+// same key to the same channel, as determined by a Partitioner using the given
+// key function. Each transformer then handles the rows for a given key in the
+// order in which they appear in the CSV file, while rows with different keys
+// are still handled concurrently. For example, to process the rows for each
+// device in order:
+//
+//	key := func(parameters CSVTransformerParameters) string {
+//	  return parameters.Input["device_id"]
+//	}
+//	generate, err := MakePartitionedCSVGenerator(reader, headers, 1, key)
+//
+// Partitioning has no effect when the batch uses SharedQueue dispatch. To
+// partition the rows sent by any other generator, such as
+// MakeCSVGeneratorWithSchema, use BatchOptions.Key instead.
+//
+// See BatchOptions, MakeCSVGeneratorWithErrors, Partitioner,
+// ProcessBatchWithErrors
+func MakePartitionedCSVGenerator(
+
+	reader *csv.Reader,
+	headers []string,
+	startRow int,
+	key func(CSVTransformerParameters) string,
+
+) (
+
+	generator func(context.Context, []chan<- CSVTransformerParameters) error,
+	err error,
+
+) {
+
+	generator = makeCSVGenerator(reader, headers, startRow, key)
+	return
+}
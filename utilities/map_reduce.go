@@ -0,0 +1,170 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"sync"
+)
+
+// Like ProcessBatchWithErrors, but rather than passing each output to a single
+// consumer, each transformer folds the values it receives into its own partial
+// accumulator, starting with the value returned by initial, using accumulate.
+// Once every value has been processed, the partial accumulators are merged
+// using combine and the result returned. This spreads the work of a reduction,
+// such as summing a column or counting values by label, across all of the
+// transformers. For example:
+//
+//	counts, err := MapReduce(
+//	  ctx,
+//	  BatchOptions{NumTransformers: 8, Dispatch: SharedQueue},
+//	  generate,
+//	  func() map[string]int { return map[string]int{} },
+//	  func(_ context.Context, counts map[string]int, row Row) (map[string]int, error) {
+//	    counts[row.Label]++
+//	    return counts, nil
+//	  },
+//	  func(a, b map[string]int) map[string]int {
+//	    for k, v := range b {
+//	      a[k] += v
+//	    }
+//	    return a
+//	  },
+//	)
+//
+// Each partial accumulator is only accessed by its own transformer, so
+// accumulate may modify it in place. Since the order in which values are
+// accumulated and partials combined is not defined, combine should be
+// associative and commutative.
+//
+// Errors are handled according to options.ErrorMode as for
+// ProcessBatchWithErrors. A value for which accumulate returns an error leaves
+// the partial accumulator as it was, unless accumulate modified it in place
+// before failing, so the result reflects only the values that were
+// successfully accumulated. The options' Dispatch, Key, RecoverPanics, Limiter
+// and Observer (reporting the "transform" stage) apply as for
+// ProcessBatchWithErrors, so Key can be used to accumulate all of the values
+// with a given key in the same partial accumulator. ConsumerBufferSize, Ordered
+// and ReorderBufferSize are ignored.
+//
+// See BatchOptions, ItemError, ProcessBatchWithErrors
+func MapReduce[Input any, Accumulator any](
+
+	ctx context.Context,
+	options BatchOptions,
+	generate func(context.Context, []chan<- Input) error,
+	initial func() Accumulator,
+	accumulate func(context.Context, Accumulator, Input) (Accumulator, error),
+	combine func(Accumulator, Accumulator) Accumulator,
+
+) (
+
+	result Accumulator,
+	err error,
+
+) {
+
+	b := newBatch(ctx, options.ErrorMode)
+	defer b.cancel()
+
+	n := options.NumTransformers
+	partials := make([]Accumulator, n)
+
+	var workers []chan<- indexed[Input]
+
+	s := stageObserver{
+		observer: options.Observer,
+		stage:    "transform",
+		depth:    func() int { return queueDepth(workers) },
+	}
+
+	// return a handler which folds each value it is passed into the i'th
+	// partial accumulator
+	handler := func(i int) func(context.Context, indexed[Input]) {
+
+		partials[i] = initial()
+
+		step := func(ctx context.Context, input Input) (Accumulator, error) {
+			return accumulate(ctx, partials[i], input)
+		}
+
+		if options.RecoverPanics {
+			step = recoverTransform(step)
+		}
+
+		if options.Limiter != nil {
+			step = limitTransform(options.Limiter, step)
+		}
+
+		return func(ctx context.Context, input indexed[Input]) {
+			received := s.received()
+			partial, err := step(ctx, input.value)
+			s.finishedItem(received, err)
+			if err != nil {
+				b.fail(&ItemError{Index: input.index, Err: err})
+				return
+			}
+			partials[i] = partial
+		}
+	}
+
+	func() {
+
+		s.started(n)
+		defer s.finished()
+
+		awaitWorkers := &sync.WaitGroup{}
+		awaitWorkers.Add(n)
+
+		switch options.Dispatch {
+
+		case SharedQueue:
+			shared := make(chan indexed[Input], options.TransformersBufferSize)
+			workers = []chan<- indexed[Input]{shared}
+			for i := range n {
+				go func() {
+					defer awaitWorkers.Done()
+					work(b.ctx, shared, handler(i))
+				}()
+			}
+
+		default:
+			workers = make([]chan<- indexed[Input], n)
+			for i := range n {
+				c := make(chan indexed[Input], options.TransformersBufferSize)
+				workers[i] = c
+				go func() {
+					defer awaitWorkers.Done()
+					work(b.ctx, c, handler(i))
+				}()
+			}
+		}
+
+		defer CloseAllAndWait(workers, awaitWorkers)
+
+		// tag each value sent by generate with its index before forwarding
+		// it to the corresponding transformer channel
+		transformers, awaitSequencer := sequence(workers, nil, keyRoute[Input](options, n))
+		defer func() {
+			for _, t := range transformers {
+				close(t)
+			}
+			<-awaitSequencer
+		}()
+
+		if err := generate(b.ctx, transformers); err != nil {
+			b.fail(err)
+		}
+	}()
+
+	for i, partial := range partials {
+		if i == 0 {
+			result = partial
+		} else {
+			result = combine(result, partial)
+		}
+	}
+
+	err = b.err(ctx)
+	return
+}
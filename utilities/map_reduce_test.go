@@ -0,0 +1,108 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"parasaurolophus/utilities"
+	"testing"
+)
+
+func generateInts(n int) func(context.Context, []chan<- int) error {
+	return func(ctx context.Context, transformers []chan<- int) error {
+		for i := range n {
+			select {
+			case transformers[i%len(transformers)] <- i + 1:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+func sum(a, b int) int {
+	return a + b
+}
+
+func TestMapReduceSum(t *testing.T) {
+	for _, dispatch := range []utilities.DispatchMode{utilities.RoundRobin, utilities.SharedQueue} {
+		options := utilities.BatchOptions{NumTransformers: 4, Dispatch: dispatch}
+		result, err := utilities.MapReduce(
+			context.Background(),
+			options,
+			generateInts(100),
+			func() int { return 0 },
+			func(_ context.Context, acc int, n int) (int, error) { return acc + n, nil },
+			sum,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != 5050 {
+			t.Errorf("dispatch %d: expected 5050, got %d", dispatch, result)
+		}
+	}
+}
+
+func TestMapReduceCounts(t *testing.T) {
+	options := utilities.BatchOptions{NumTransformers: 3}
+	result, err := utilities.MapReduce(
+		context.Background(),
+		options,
+		generateInts(10),
+		func() map[string]int { return map[string]int{} },
+		func(_ context.Context, counts map[string]int, n int) (map[string]int, error) {
+			counts[fmt.Sprint(n%2 == 0)]++
+			return counts, nil
+		},
+		func(a, b map[string]int) map[string]int {
+			for k, v := range b {
+				a[k] += v
+			}
+			return a
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]int{"true": 5, "false": 5}; !maps.Equal(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestMapReduceErrors(t *testing.T) {
+	options := utilities.BatchOptions{NumTransformers: 2, ErrorMode: utilities.CollectAll, RecoverPanics: true}
+	result, err := utilities.MapReduce(
+		context.Background(),
+		options,
+		generateInts(10),
+		func() int { return 0 },
+		func(_ context.Context, acc int, n int) (int, error) {
+			switch n {
+			case 3:
+				return acc, errors.New("three")
+			case 7:
+				panic("seven")
+			}
+			return acc + n, nil
+		},
+		sum,
+	)
+	if result != 45 {
+		t.Errorf("expected 45, got %d", result)
+	}
+	indices := []int{}
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		itemError := &utilities.ItemError{}
+		if errors.As(e, &itemError) {
+			indices = append(indices, itemError.Index)
+		}
+	}
+	if len(indices) != 2 || indices[0] != 2 || indices[1] != 6 {
+		t.Errorf("expected errors for indices 2 and 6, got %v", err)
+	}
+}
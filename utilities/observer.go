@@ -0,0 +1,247 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+
+	// Receives notifications of the progress of each stage of a batch or
+	// pipeline, e.g. the "transform" and "consume" stages of
+	// ProcessBatchWithErrors. Methods may be invoked concurrently from
+	// multiple goroutines.
+	//
+	// See BatchOptions, StageOptions, StartObservedWorkers, SummaryObserver
+	Observer interface {
+
+		// Called once for each stage before any of its items are received.
+		StageStarted(stage string, numWorkers int)
+
+		// Called when one of a stage's workers receives an item, with the
+		// number of items still waiting in the stage's queues.
+		ItemReceived(stage string, queueDepth int)
+
+		// Called when one of a stage's workers finishes with an item, with
+		// the time it took and the resulting error, if any.
+		ItemFinished(stage string, latency time.Duration, err error)
+
+		// Called once for each stage after all of its workers have exited.
+		StageFinished(stage string)
+	}
+
+	// Statistics for one stage gathered by a SummaryObserver.
+	//
+	// See SummaryObserver
+	StageSummary struct {
+		Stage          string
+		NumWorkers     int
+		ItemsIn        int
+		ItemsOut       int
+		Errors         int
+		MaxQueueDepth  int
+		MeanQueueDepth float64
+		MeanLatency    time.Duration
+		MaxLatency     time.Duration
+		Elapsed        time.Duration
+
+		// Fraction of the stage's elapsed time its workers spent handling
+		// items, between 0 and 1.
+		Utilization float64
+
+		totalQueueDepth int
+		totalLatency    time.Duration
+		started         time.Time
+		finished        time.Time
+	}
+
+	// An Observer which gathers summary statistics for each stage, to help
+	// decide, for example, whether a batch would benefit from more
+	// transformers or larger buffers. For example:
+	//
+	//	summary := &SummaryObserver{}
+	//	options.Observer = summary
+	//	err := ProcessBatchWithErrors(ctx, options, generate, transform, consume)
+	//	fmt.Println(summary)
+	//
+	// See Observer, StageSummary
+	SummaryObserver struct {
+		lock   sync.Mutex
+		stages []*StageSummary
+	}
+)
+
+func (observer *SummaryObserver) StageStarted(stage string, numWorkers int) {
+
+	defer observer.lock.Unlock()
+	observer.lock.Lock()
+
+	observer.stages = append(observer.stages, &StageSummary{
+		Stage:      stage,
+		NumWorkers: numWorkers,
+		started:    time.Now(),
+	})
+}
+
+func (observer *SummaryObserver) ItemReceived(stage string, queueDepth int) {
+
+	defer observer.lock.Unlock()
+	observer.lock.Lock()
+
+	if s := observer.stage(stage); s != nil {
+		s.ItemsIn++
+		s.totalQueueDepth += queueDepth
+		s.MaxQueueDepth = max(s.MaxQueueDepth, queueDepth)
+	}
+}
+
+func (observer *SummaryObserver) ItemFinished(stage string, latency time.Duration, err error) {
+
+	defer observer.lock.Unlock()
+	observer.lock.Lock()
+
+	if s := observer.stage(stage); s != nil {
+		if err != nil {
+			s.Errors++
+		} else {
+			s.ItemsOut++
+		}
+		s.totalLatency += latency
+		s.MaxLatency = max(s.MaxLatency, latency)
+	}
+}
+
+func (observer *SummaryObserver) StageFinished(stage string) {
+
+	defer observer.lock.Unlock()
+	observer.lock.Lock()
+
+	if s := observer.stage(stage); s != nil {
+		s.finished = time.Now()
+	}
+}
+
+// Return the statistics gathered so far for each stage, in the order in which
+// the stages started.
+func (observer *SummaryObserver) Summary() []StageSummary {
+
+	defer observer.lock.Unlock()
+	observer.lock.Lock()
+
+	summary := make([]StageSummary, len(observer.stages))
+
+	for i, s := range observer.stages {
+		summary[i] = *s
+		finished := s.finished
+		if finished.IsZero() {
+			finished = time.Now()
+		}
+		summary[i].Elapsed = finished.Sub(s.started)
+		if s.ItemsIn > 0 {
+			summary[i].MeanQueueDepth = float64(s.totalQueueDepth) / float64(s.ItemsIn)
+		}
+		if n := s.ItemsOut + s.Errors; n > 0 {
+			summary[i].MeanLatency = s.totalLatency / time.Duration(n)
+		}
+		if capacity := summary[i].Elapsed * time.Duration(s.NumWorkers); capacity > 0 {
+			summary[i].Utilization = min(float64(s.totalLatency)/float64(capacity), 1)
+		}
+	}
+
+	return summary
+}
+
+// Return a report of the statistics gathered so far, one line per stage.
+func (observer *SummaryObserver) String() string {
+
+	builder := strings.Builder{}
+
+	for _, s := range observer.Summary() {
+		fmt.Fprintf(
+			&builder,
+			"%s: %d workers, %d in, %d out, %d errors, queue depth mean %.1f max %d, latency mean %s max %s, %.0f%% utilization over %s\n",
+			s.Stage,
+			s.NumWorkers,
+			s.ItemsIn,
+			s.ItemsOut,
+			s.Errors,
+			s.MeanQueueDepth,
+			s.MaxQueueDepth,
+			s.MeanLatency,
+			s.MaxLatency,
+			s.Utilization*100,
+			s.Elapsed,
+		)
+	}
+
+	return builder.String()
+}
+
+// Return the most recently started stage with the given name, if any.
+func (observer *SummaryObserver) stage(stage string) *StageSummary {
+
+	for i := len(observer.stages) - 1; i >= 0; i-- {
+		if observer.stages[i].Stage == stage {
+			return observer.stages[i]
+		}
+	}
+
+	return nil
+}
+
+type (
+
+	// Reports to an Observer, if there is one, on behalf of a stage.
+	stageObserver struct {
+		observer Observer
+		stage    string
+		depth    func() int
+	}
+)
+
+func (s stageObserver) started(numWorkers int) {
+
+	if s.observer != nil {
+		s.observer.StageStarted(s.stage, numWorkers)
+	}
+}
+
+// Report that an item was received and return the time at which it was.
+func (s stageObserver) received() time.Time {
+
+	if s.observer != nil {
+		s.observer.ItemReceived(s.stage, s.depth())
+	}
+
+	return time.Now()
+}
+
+func (s stageObserver) finishedItem(received time.Time, err error) {
+
+	if s.observer != nil {
+		s.observer.ItemFinished(s.stage, time.Since(received), err)
+	}
+}
+
+func (s stageObserver) finished() {
+
+	if s.observer != nil {
+		s.observer.StageFinished(s.stage)
+	}
+}
+
+// Return the total number of values waiting in the given channels.
+func queueDepth[V any](channels []chan<- V) int {
+
+	depth := 0
+
+	for _, c := range channels {
+		depth += len(c)
+	}
+
+	return depth
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"parasaurolophus/utilities"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummaryObserverProcessBatch(t *testing.T) {
+	summary := &utilities.SummaryObserver{}
+	options := utilities.BatchOptions{
+		NumTransformers: 3,
+		ErrorMode:       utilities.CollectAll,
+		Observer:        summary,
+	}
+	generate := func(_ context.Context, transformers []chan<- int) error {
+		for i := range 10 {
+			transformers[i%len(transformers)] <- i
+		}
+		return nil
+	}
+	transform := func(_ context.Context, n int) (int, error) {
+		time.Sleep(time.Millisecond)
+		if n == 7 {
+			return 0, fmt.Errorf("%d", n)
+		}
+		return n, nil
+	}
+	consume := func(context.Context, int) error {
+		return nil
+	}
+	if err := utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume); err == nil {
+		t.Fatal("expected an error")
+	}
+	stages := summary.Summary()
+	if len(stages) != 2 {
+		t.Fatalf("expected 2 stages, got %d", len(stages))
+	}
+	for _, s := range stages {
+		switch s.Stage {
+		case "transform":
+			if s.NumWorkers != 3 || s.ItemsIn != 10 || s.ItemsOut != 9 || s.Errors != 1 {
+				t.Errorf("unexpected transform summary %+v", s)
+			}
+			if s.MeanLatency < time.Millisecond || s.MaxLatency < s.MeanLatency {
+				t.Errorf("unexpected transform latency %+v", s)
+			}
+			if s.Utilization <= 0 || s.Utilization > 1 {
+				t.Errorf("unexpected transform utilization %f", s.Utilization)
+			}
+		case "consume":
+			if s.NumWorkers != 1 || s.ItemsIn != 9 || s.ItemsOut != 9 || s.Errors != 0 {
+				t.Errorf("unexpected consume summary %+v", s)
+			}
+		default:
+			t.Errorf("unexpected stage %s", s.Stage)
+		}
+	}
+	report := summary.String()
+	if !strings.Contains(report, "transform: 3 workers, 10 in, 9 out, 1 errors") {
+		t.Errorf("unexpected report %q", report)
+	}
+}
+
+func TestSummaryObserverPipeline(t *testing.T) {
+	summary := &utilities.SummaryObserver{}
+	generate := func(_ context.Context, values chan<- int) error {
+		for i := range 5 {
+			values <- i
+		}
+		return nil
+	}
+	double := func(_ context.Context, n int) (int, error) {
+		return n * 2, nil
+	}
+	fail := func(_ context.Context, n int) (int, error) {
+		if n > 4 {
+			return 0, errors.New("too big")
+		}
+		return n, nil
+	}
+	pipeline := utilities.AddStage(
+		utilities.AddStage(
+			utilities.NewPipeline(generate),
+			utilities.StageOptions{NumWorkers: 2, Observer: summary},
+			double,
+		),
+		utilities.StageOptions{NumWorkers: 1, Observer: summary, Name: "check"},
+		fail,
+	)
+	pipeline.Run(context.Background(), utilities.CollectAll, func(context.Context, int) error { return nil })
+	stages := map[string]utilities.StageSummary{}
+	for _, s := range summary.Summary() {
+		stages[s.Stage] = s
+	}
+	if s := stages["stage 1"]; s.ItemsIn != 5 || s.ItemsOut != 5 || s.NumWorkers != 2 {
+		t.Errorf("unexpected first stage summary %+v", s)
+	}
+	if s := stages["check"]; s.ItemsIn != 5 || s.ItemsOut != 3 || s.Errors != 2 {
+		t.Errorf("unexpected second stage summary %+v", s)
+	}
+}
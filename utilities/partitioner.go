@@ -0,0 +1,129 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"hash/fnv"
+	"slices"
+	"strconv"
+)
+
+// Number of points on a Partitioner's hash ring for each partition. More
+// points spread keys more evenly among the partitions.
+const pointsPerPartition = 64
+
+type (
+
+	// Maps values to partitions by key using consistent hashing, so that all
+	// values with the same key are assigned to the same partition. Used to
+	// route values to one of the channels returned by StartWorkers or passed
+	// to a ProcessBatch generate function, such that values with the same key
+	// are handled by the same worker in the order they were sent while values
+	// with different keys are still handled concurrently. For example:
+	//
+	//	{
+	//	  values, await := StartWorkers(numWorkers, bufferSize, handler)
+	//	  defer CloseAllAndWait(values, await)
+	//	  partitioner := NewPartitioner(numWorkers, key)
+	//	  for _, value := range data {
+	//	    partitioner.Send(values, value)
+	//	  }
+	//	}
+	//
+	// Compared to hashing keys modulo the number of partitions, consistent
+	// hashing changes the partition of only a small fraction of keys when the
+	// number of partitions changes.
+	//
+	// See MakePartitionedCSVGenerator, NewPartitioner
+	Partitioner[V any] struct {
+		key    func(V) string
+		points []ringPoint
+	}
+
+	// A point on a Partitioner's hash ring.
+	ringPoint struct {
+		hash      uint32
+		partition int
+	}
+)
+
+// Return a Partitioner which assigns values to partitions numbered from 0 to
+// numPartitions-1 according to the result of applying the given key function
+// to them. There is always at least one partition, so numPartitions is
+// treated as one if it is less than that.
+//
+// See Partitioner
+func NewPartitioner[V any](numPartitions int, key func(V) string) *Partitioner[V] {
+
+	numPartitions = max(numPartitions, 1)
+
+	partitioner := &Partitioner[V]{
+		key:    key,
+		points: make([]ringPoint, 0, numPartitions*pointsPerPartition),
+	}
+
+	for partition := range numPartitions {
+		for i := range pointsPerPartition {
+			point := ringPoint{
+				hash:      hashKey(strconv.Itoa(partition) + "#" + strconv.Itoa(i)),
+				partition: partition,
+			}
+			partitioner.points = append(partitioner.points, point)
+		}
+	}
+
+	slices.SortFunc(partitioner.points, func(x, y ringPoint) int {
+		switch {
+		case x.hash < y.hash:
+			return -1
+		case x.hash > y.hash:
+			return 1
+		default:
+			return x.partition - y.partition
+		}
+	})
+
+	return partitioner
+}
+
+// Return the partition to which the given value is assigned, i.e. the one
+// which owns the first point on the hash ring at or after the hash of its key.
+func (partitioner *Partitioner[V]) Partition(value V) int {
+
+	hash := hashKey(partitioner.key(value))
+
+	i, _ := slices.BinarySearchFunc(partitioner.points, hash, func(point ringPoint, hash uint32) int {
+		switch {
+		case point.hash < hash:
+			return -1
+		case point.hash > hash:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	if i == len(partitioner.points) {
+		i = 0
+	}
+
+	return partitioner.points[i].partition
+}
+
+// Send the given value to the channel corresponding to its partition. The
+// number of channels should equal the number of partitions. Otherwise, the
+// partition is taken modulo the number of channels, so values with the same
+// key are still sent to the same channel but keys are spread less evenly.
+// There must be at least one channel.
+func (partitioner *Partitioner[V]) Send(channels []chan<- V, value V) {
+
+	channels[partitioner.Partition(value)%len(channels)] <- value
+}
+
+// Return the 32-bit FNV-1a hash of the given key.
+func hashKey(key string) uint32 {
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"math/rand/v2"
+	"parasaurolophus/utilities"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPartitioner(t *testing.T) {
+	key := func(n int) string {
+		return strconv.Itoa(n % 100)
+	}
+	partitioner := utilities.NewPartitioner(4, key)
+	counts := make([]int, 4)
+	for i := range 1000 {
+		p := partitioner.Partition(i)
+		if p < 0 || p >= 4 {
+			t.Fatalf("expected partition in [0, 4), got %d", p)
+		}
+		if q := partitioner.Partition(i + 100); p != q {
+			t.Errorf("expected %d and %d to be in the same partition, got %d and %d", i, i+100, p, q)
+		}
+		counts[p]++
+	}
+	for p, count := range counts {
+		if count == 0 {
+			t.Errorf("expected some values in partition %d", p)
+		}
+	}
+}
+
+func TestPartitionerConsistency(t *testing.T) {
+	key := func(s string) string {
+		return s
+	}
+	before := utilities.NewPartitioner(8, key)
+	after := utilities.NewPartitioner(9, key)
+	moved := 0
+	for i := range 1000 {
+		s := strconv.Itoa(i)
+		if p, q := before.Partition(s), after.Partition(s); p != q {
+			if q != 8 {
+				t.Errorf("expected %s to move only to the new partition, moved from %d to %d", s, p, q)
+			}
+			moved++
+		}
+	}
+	if moved > 250 {
+		t.Errorf("expected about 1 in 9 keys to move, %d of 1000 did", moved)
+	}
+}
+
+func TestPartitionerStartWorkers(t *testing.T) {
+	type value struct {
+		key string
+		seq int
+	}
+	actual := map[string][]int{}
+	lock := sync.Mutex{}
+	handler := func(v value) {
+		time.Sleep(time.Duration(rand.IntN(100)) * time.Microsecond)
+		defer lock.Unlock()
+		lock.Lock()
+		actual[v.key] = append(actual[v.key], v.seq)
+	}
+	key := func(v value) string {
+		return v.key
+	}
+	func() {
+		values, await := utilities.StartWorkers(3, 1, handler)
+		defer utilities.CloseAllAndWait(values, await)
+		partitioner := utilities.NewPartitioner(len(values), key)
+		for i := range 100 {
+			partitioner.Send(values, value{key: strconv.Itoa(i % 7), seq: i})
+		}
+	}()
+	for k, seqs := range actual {
+		for i := 1; i < len(seqs); i++ {
+			if seqs[i] < seqs[i-1] {
+				t.Errorf("expected values for key %s in order, got %v", k, seqs)
+				break
+			}
+		}
+	}
+}
+
+func TestPartitionerBounds(t *testing.T) {
+	key := func(n int) string {
+		return strconv.Itoa(n)
+	}
+	partitioner := utilities.NewPartitioner(0, key)
+	for i := range 100 {
+		if p := partitioner.Partition(i); p != 0 {
+			t.Fatalf("expected a single partition, got %d", p)
+		}
+	}
+	partitioner = utilities.NewPartitioner(4, key)
+	channel := make(chan int, 100)
+	for i := range 100 {
+		partitioner.Send([]chan<- int{channel}, i)
+	}
+	if len(channel) != 100 {
+		t.Errorf("expected 100 values on the only channel, got %d", len(channel))
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type (
+
+	// A chain of concurrent stages, each of which transforms the values
+	// produced by the one before it. Use NewPipeline to create the first
+	// stage, AddStage to append each subsequent one and Run to process the
+	// values produced by the last one. For example:
+	//
+	//	parsed := NewPipeline(generate)
+	//	enriched := AddStage(parsed, StageOptions{NumWorkers: 8}, enrich)
+	//	validated := AddStage(enriched, StageOptions{NumWorkers: 2, Ordered: true}, validate)
+	//	err := validated.Run(ctx, FailFast, write)
+	//
+	// See AddStage, NewPipeline, ProcessBatchWithErrors, StageOptions
+	Pipeline[Output any] struct {
+		start  func(*batch) <-chan indexed[Output]
+		stages int
+	}
+
+	// Parameters for a stage added to a Pipeline.
+	//
+	// See AddStage, Pipeline
+	StageOptions struct {
+
+		// Number of worker goroutines for the stage. Defaults to one.
+		NumWorkers int
+
+		// Size of each worker goroutine's channel buffer, or of the shared
+		// one in SharedQueue mode.
+		BufferSize int
+
+		// Whether values are sent to each worker in turn or to a single
+		// queue shared by all of them.
+		Dispatch DispatchMode
+
+		// Whether the stage passes on its outputs in the same order in which
+		// it received the corresponding inputs. A pipeline's outputs are in
+		// the order generated only if every stage with more than one worker
+		// is ordered.
+		Ordered bool
+
+		// Maximum number of outputs held while waiting for earlier ones when
+		// Ordered is true. Defaults to NumWorkers.
+		ReorderBufferSize int
+
+		// Whether a panic in the stage's transform function is reported as
+		// an error for the item being processed rather than terminating the
+		// process.
+		RecoverPanics bool
+
+		// If not nil, limits the rate and concurrency with which the stage's
+		// transform function is invoked across all of its workers.
+		Limiter *Limiter
+
+		// If not nil, is notified of the progress of the stage.
+		Observer Observer
+
+		// Name by which the stage is reported to Observer. Defaults to
+		// "stage 1" for the first stage added to a pipeline, "stage 2" for
+		// the second and so on.
+		Name string
+	}
+)
+
+// Return a Pipeline whose values are those sent by the given generate function
+// to the channel it is passed. The channel is closed when generate returns.
+// Like the generate function passed to ProcessBatchContext, generate should
+// return promptly once its context is done.
+//
+// See AddStage, Pipeline
+func NewPipeline[Output any](
+
+	generate func(context.Context, chan<- Output) error,
+
+) *Pipeline[Output] {
+
+	start := func(b *batch) <-chan indexed[Output] {
+
+		values := make(chan Output)
+		outputs := make(chan indexed[Output])
+
+		// tag each value sent by generate with its index
+		go func() {
+			defer close(outputs)
+			index := 0
+			for value := range values {
+				outputs <- indexed[Output]{index: index, value: value}
+				index++
+			}
+		}()
+
+		go func() {
+			defer close(values)
+			if err := generate(b.ctx, values); err != nil {
+				b.fail(err)
+			}
+		}()
+
+		return outputs
+	}
+
+	return &Pipeline[Output]{start: start}
+}
+
+// Return a Pipeline whose values are the result of applying the given
+// transform function to each of the values of the given one, using a pool of
+// worker goroutines configured by the given options. Errors returned by
+// transform are wrapped in an ItemError whose Index is the position of the
+// failed item in the sequence of values sent by the pipeline's generate
+// function. Such items are not passed on to the next stage.
+//
+// Each stage starts shutting down once the one before it has closed its output
+// channel. It then closes its workers' channels, waits for them to exit and
+// closes its own output channel in turn, so the stages shut down in order.
+//
+// If options.Observer is not nil, it is notified of the stage's progress under
+// options.Name each time the pipeline is run.
+//
+// See NewPipeline, Observer, Pipeline, StageOptions
+func AddStage[Input any, Output any](
+
+	pipeline *Pipeline[Input],
+	options StageOptions,
+	transform func(context.Context, Input) (Output, error),
+
+) *Pipeline[Output] {
+
+	options.NumWorkers = max(options.NumWorkers, 1)
+
+	if options.RecoverPanics {
+		transform = recoverTransform(transform)
+	}
+
+	if options.Limiter != nil {
+		transform = limitTransform(options.Limiter, transform)
+	}
+
+	stages := pipeline.stages + 1
+
+	if options.Name == "" {
+		options.Name = fmt.Sprintf("stage %d", stages)
+	}
+
+	start := func(b *batch) <-chan indexed[Output] {
+
+		var workers []chan<- indexed[indexed[Input]]
+
+		s := stageObserver{
+			observer: options.Observer,
+			stage:    options.Name,
+			depth:    func() int { return queueDepth(workers) },
+		}
+
+		inputs := pipeline.start(b)
+		outputs := make(chan indexed[Output])
+
+		// results are tagged with the order in which the corresponding inputs
+		// were received by this stage, which is the order in which they are
+		// passed on when options.Ordered is true
+		results := make(chan indexed[indexed[Output]])
+
+		produce := func(ctx context.Context, input indexed[indexed[Input]]) {
+			received := s.received()
+			output, err := transform(ctx, input.value.value)
+			s.finishedItem(received, err)
+			result := indexed[indexed[Output]]{
+				index: input.index,
+				value: indexed[Output]{index: input.value.index, value: output},
+			}
+			if err != nil {
+				b.fail(&ItemError{Index: input.value.index, Err: err})
+				result.skip = true
+			}
+			results <- result
+		}
+
+		collect := func(result indexed[indexed[Output]]) {
+			if !result.skip {
+				outputs <- result.value
+			}
+		}
+
+		var gate func(int)
+
+		if options.Ordered {
+			size := options.ReorderBufferSize
+			if size < 1 {
+				size = options.NumWorkers
+			}
+			reorder := newReorderBuffer[indexed[Output]](b.ctx, size)
+			gate = func(seq int) {
+				reorder.wait(b.ctx, seq)
+			}
+			collectInOrder := collect
+			collect = func(result indexed[indexed[Output]]) {
+				reorder.add(result, collectInOrder)
+			}
+		}
+
+		var awaitWorkers *sync.WaitGroup
+
+		s.started(options.NumWorkers)
+
+		switch options.Dispatch {
+
+		case SharedQueue:
+			var shared chan<- indexed[indexed[Input]]
+			shared, awaitWorkers = StartSharedWorkersContext(b.ctx, options.NumWorkers, options.BufferSize, produce)
+			workers = []chan<- indexed[indexed[Input]]{shared}
+
+		default:
+			workers, awaitWorkers = StartWorkersContext(b.ctx, options.NumWorkers, options.BufferSize, produce)
+		}
+
+		// dispatch inputs to the workers until the previous stage closes its
+		// output channel, then shut down the workers
+		go func() {
+			defer close(results)
+			defer s.finished()
+			defer CloseAllAndWait(workers, awaitWorkers)
+			n := len(workers)
+			seq := 0
+			for input := range inputs {
+				if gate != nil {
+					gate(seq)
+				}
+				workers[seq%n] <- indexed[indexed[Input]]{index: seq, value: input}
+				seq++
+			}
+		}()
+
+		// pass results on to the next stage
+		go func() {
+			defer close(outputs)
+			for result := range results {
+				collect(result)
+			}
+		}()
+
+		return outputs
+	}
+
+	return &Pipeline[Output]{start: start, stages: stages}
+}
+
+// Start all of the stages of the given pipeline and apply the given consume
+// function to each value produced by the last of them, in the calling
+// goroutine. Returns once every stage has shut down, with errors reported as
+// for ProcessBatchWithErrors according to the given mode. A Pipeline may be
+// run more than once, so long as its generate function can be invoked more
+// than once.
+//
+// See AddStage, NewPipeline, ProcessBatchWithErrors
+func (pipeline *Pipeline[Output]) Run(
+
+	ctx context.Context,
+	errorMode ErrorMode,
+	consume func(context.Context, Output) error,
+
+) error {
+
+	b := newBatch(ctx, errorMode)
+	defer b.cancel()
+
+	for output := range pipeline.start(b) {
+		if b.ctx.Err() != nil {
+			continue
+		}
+		if err := consume(b.ctx, output.value); err != nil {
+			b.fail(&ItemError{Index: output.index, Err: err})
+		}
+	}
+
+	return b.err(ctx)
+}
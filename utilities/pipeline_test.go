@@ -0,0 +1,135 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"parasaurolophus/utilities"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPipeline(t *testing.T) {
+	actual := []string{}
+	generate := func(_ context.Context, values chan<- string) error {
+		for i := range 50 {
+			values <- strconv.Itoa(i)
+		}
+		return nil
+	}
+	parse := func(_ context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	}
+	double := func(_ context.Context, n int) (int, error) {
+		time.Sleep(time.Duration(rand.IntN(200)) * time.Microsecond)
+		return n * 2, nil
+	}
+	format := func(_ context.Context, n int) (string, error) {
+		return fmt.Sprintf("%03d", n), nil
+	}
+	parsed := utilities.NewPipeline(generate)
+	doubled := utilities.AddStage(parsed, utilities.StageOptions{NumWorkers: 2, Ordered: true}, parse)
+	doubled = utilities.AddStage(doubled, utilities.StageOptions{NumWorkers: 5, BufferSize: 2, Ordered: true}, double)
+	formatted := utilities.AddStage(doubled, utilities.StageOptions{NumWorkers: 1, Dispatch: utilities.SharedQueue}, format)
+	consume := func(_ context.Context, s string) error {
+		actual = append(actual, s)
+		return nil
+	}
+	err := formatted.Run(context.Background(), utilities.FailFast, consume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != 50 {
+		t.Fatalf("expected 50, got %d", len(actual))
+	}
+	if !slices.IsSorted(actual) {
+		t.Errorf("expected outputs in order, got %v", actual)
+	}
+	if actual[49] != "098" {
+		t.Errorf(`expected "098", got "%s"`, actual[49])
+	}
+}
+
+func TestPipelineFailFast(t *testing.T) {
+	generate := func(ctx context.Context, values chan<- string) error {
+		for i := 0; ctx.Err() == nil; i++ {
+			if i == 7 {
+				values <- "seven"
+			} else {
+				values <- strconv.Itoa(i)
+			}
+		}
+		return nil
+	}
+	parse := func(_ context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	}
+	pipeline := utilities.AddStage(utilities.NewPipeline(generate), utilities.StageOptions{NumWorkers: 3}, parse)
+	consume := func(context.Context, int) error {
+		return nil
+	}
+	err := pipeline.Run(context.Background(), utilities.FailFast, consume)
+	var itemError *utilities.ItemError
+	if !errors.As(err, &itemError) {
+		t.Fatalf("expected an ItemError, got %v", err)
+	}
+	if itemError.Index != 7 {
+		t.Errorf("expected index 7, got %d", itemError.Index)
+	}
+}
+
+func TestPipelineCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	generate := func(ctx context.Context, values chan<- int) error {
+		for i := 0; ctx.Err() == nil; i++ {
+			values <- i
+		}
+		return nil
+	}
+	identity := func(_ context.Context, n int) (int, error) {
+		return n, nil
+	}
+	pipeline := utilities.NewPipeline(generate)
+	for range 3 {
+		pipeline = utilities.AddStage(pipeline, utilities.StageOptions{NumWorkers: 2, Ordered: true}, identity)
+	}
+	consume := func(context.Context, int) error {
+		return nil
+	}
+	err := pipeline.Run(ctx, utilities.CollectAll, consume)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestPipelineDefaultWorkers(t *testing.T) {
+	generate := func(_ context.Context, values chan<- int) error {
+		for i := range 10 {
+			values <- i
+		}
+		return nil
+	}
+	increment := func(_ context.Context, n int) (int, error) {
+		return n + 1, nil
+	}
+	for _, dispatch := range []utilities.DispatchMode{utilities.RoundRobin, utilities.SharedQueue} {
+		actual := []int{}
+		pipeline := utilities.AddStage(utilities.NewPipeline(generate), utilities.StageOptions{Dispatch: dispatch}, increment)
+		consume := func(_ context.Context, n int) error {
+			actual = append(actual, n)
+			return nil
+		}
+		if err := pipeline.Run(context.Background(), utilities.FailFast, consume); err != nil {
+			t.Fatal(err)
+		}
+		if expected := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}; !slices.Equal(actual, expected) {
+			t.Errorf("dispatch %d: expected %v, got %v", dispatch, expected, actual)
+		}
+	}
+}
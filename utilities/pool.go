@@ -0,0 +1,212 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+
+	// Parameters for StartPool.
+	//
+	// See StartPool
+	PoolOptions struct {
+
+		// Bounds on the number of worker goroutines. The pool starts with
+		// MinWorkers, which must be at least one.
+		MinWorkers int
+		MaxWorkers int
+
+		// Size of the buffer of the channel shared by all of the workers.
+		BufferSize int
+
+		// If not zero, how often to adjust the number of workers according
+		// to the number of values waiting in the channel's buffer: adding
+		// workers while values are waiting and removing idle ones otherwise.
+		ScaleInterval time.Duration
+	}
+
+	// A group of worker goroutines whose number can change while they run.
+	//
+	// See StartPool
+	Pool[V any] struct {
+		lock    sync.Mutex
+		options PoolOptions
+		handler func(V)
+		values  chan V
+		retire  chan any
+		await   *sync.WaitGroup
+		size    int
+		busy    int
+		closed  bool
+		done    chan any
+	}
+)
+
+// Like StartSharedWorkers, but the number of workers can be changed using the
+// returned pool's Resize method and, if options.ScaleInterval is not zero, is
+// adjusted automatically according to the number of values waiting to be
+// handled. Workers removed from the pool finish handling their current value,
+// if any, before exiting. For example:
+//
+//	{
+//	  options := PoolOptions{MinWorkers: 1, MaxWorkers: 100, ScaleInterval: time.Second}
+//	  pool, values, await := StartPool(options, handler)
+//	  defer CloseAllAndWait([]chan<- V{values}, await)
+//	  for _, value := range data {
+//	    values <- value
+//	  }
+//	}
+//
+// See CloseAllAndWait, PoolOptions, StartSharedWorkers
+func StartPool[V any](
+
+	options PoolOptions,
+	handler func(V),
+
+) (
+
+	pool *Pool[V],
+	values chan<- V,
+	await *sync.WaitGroup,
+
+) {
+
+	options.MinWorkers = max(options.MinWorkers, 1)
+	options.MaxWorkers = max(options.MaxWorkers, options.MinWorkers)
+
+	pool = &Pool[V]{
+		options: options,
+		handler: handler,
+		values:  make(chan V, options.BufferSize),
+		retire:  make(chan any),
+		await:   &sync.WaitGroup{},
+		done:    make(chan any),
+	}
+
+	values = pool.values
+	await = pool.await
+
+	pool.Resize(options.MinWorkers)
+
+	if options.ScaleInterval > 0 {
+		go pool.scale()
+	}
+
+	return
+}
+
+// Return the current number of workers.
+func (pool *Pool[V]) Size() int {
+
+	defer pool.lock.Unlock()
+	pool.lock.Lock()
+
+	return pool.size
+}
+
+// Start or stop workers so that there are numWorkers of them, within the
+// bounds specified when the pool was started, and return the resulting
+// number. Has no effect once the pool's values channel has been closed.
+func (pool *Pool[V]) Resize(numWorkers int) int {
+
+	defer pool.lock.Unlock()
+	pool.lock.Lock()
+
+	return pool.resize(numWorkers)
+}
+
+// Body of Resize, called with the pool's lock held.
+func (pool *Pool[V]) resize(numWorkers int) int {
+
+	if pool.closed {
+		return pool.size
+	}
+
+	numWorkers = min(max(numWorkers, pool.options.MinWorkers), pool.options.MaxWorkers)
+
+	for ; pool.size < numWorkers; pool.size++ {
+		pool.await.Add(1)
+		go pool.work()
+	}
+
+	// signal surplus workers to exit as soon as they are idle, without
+	// waiting for them to do so
+	for ; pool.size > numWorkers; pool.size-- {
+		go func() {
+			select {
+			case pool.retire <- nil:
+			case <-pool.done:
+			}
+		}()
+	}
+
+	return pool.size
+}
+
+// Body of each worker goroutine.
+func (pool *Pool[V]) work() {
+
+	defer pool.await.Done()
+
+	for {
+
+		select {
+
+		case <-pool.retire:
+			return
+
+		case value, ok := <-pool.values:
+			if !ok {
+				pool.lock.Lock()
+				if !pool.closed {
+					pool.closed = true
+					close(pool.done)
+				}
+				pool.lock.Unlock()
+				return
+			}
+			pool.setBusy(1)
+			pool.handler(value)
+			pool.setBusy(-1)
+		}
+	}
+}
+
+// Adjust the number of workers that are handling a value.
+func (pool *Pool[V]) setBusy(delta int) {
+
+	defer pool.lock.Unlock()
+	pool.lock.Lock()
+
+	pool.busy += delta
+}
+
+// Periodically add a worker if values are waiting to be handled or remove one
+// if any are idle, until the pool's values channel is closed.
+func (pool *Pool[V]) scale() {
+
+	ticker := time.NewTicker(pool.options.ScaleInterval)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+
+		case <-pool.done:
+			return
+
+		case <-ticker.C:
+			pool.lock.Lock()
+			switch {
+			case len(pool.values) > 0:
+				pool.resize(pool.size + 1)
+			case pool.busy < pool.size:
+				pool.resize(pool.size - 1)
+			}
+			pool.lock.Unlock()
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"parasaurolophus/utilities"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolResize(t *testing.T) {
+	inFlight := atomic.Int32{}
+	peak := atomic.Int32{}
+	total := atomic.Int32{}
+	handler := func(n int) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if current <= p || peak.CompareAndSwap(p, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		total.Add(int32(n))
+	}
+	options := utilities.PoolOptions{MinWorkers: 1, MaxWorkers: 4}
+	pool, values, await := utilities.StartPool(options, handler)
+	if size := pool.Resize(10); size != 4 {
+		t.Errorf("expected size to be clamped to 4, got %d", size)
+	}
+	for i := range 20 {
+		values <- i
+	}
+	if size := pool.Resize(0); size != 1 {
+		t.Errorf("expected size to be clamped to 1, got %d", size)
+	}
+	time.Sleep(time.Millisecond * 10)
+	peak.Store(0)
+	for i := range 20 {
+		values <- i
+	}
+	utilities.CloseAllAndWait([]chan<- int{values}, await)
+	if peak.Load() != 1 {
+		t.Errorf("expected 1 worker after shrinking, got %d", peak.Load())
+	}
+	if total.Load() != 380 {
+		t.Errorf("expected 380, got %d", total.Load())
+	}
+	if size := pool.Resize(3); size != 1 {
+		t.Errorf("expected resizing a closed pool to have no effect, got %d", size)
+	}
+}
+
+func TestPoolScale(t *testing.T) {
+	release := make(chan any)
+	handler := func(int) {
+		<-release
+	}
+	options := utilities.PoolOptions{MinWorkers: 1, MaxWorkers: 3, BufferSize: 10, ScaleInterval: time.Millisecond}
+	pool, values, await := utilities.StartPool(options, handler)
+	defer utilities.CloseAllAndWait([]chan<- int{values}, await)
+	for i := range 10 {
+		values <- i
+	}
+	eventually(t, func() bool {
+		return pool.Size() == 3
+	})
+	close(release)
+	eventually(t, func() bool {
+		return pool.Size() == 1
+	})
+}
@@ -5,10 +5,11 @@ package utilities
 // Process items in a set of data concurrently. Specifically, start n+1
 // goroutines and wait for them all to complete after invoking the given
 // generator function. The generator function must send input values in a
-// round-robin fashion to the set of transformer channels it is passed. The
-// transformer goroutines will send the result of invoking the given transform
-// function to the consumer goroutine, which invokes the given consume
-// function:
+// round-robin fashion to the set of transformer channels it is passed, or use
+// a Partitioner to send all of the values with a given key to the same one.
+// The transformer goroutines will send the result of invoking the given
+// transform function to the consumer goroutine, which invokes the given
+// consume function:
 //
 //	                   +-----------+
 //	              +-->>| transform |----+
@@ -29,7 +30,7 @@ package utilities
 // the batch to run to completion even if some operations would otherwise block
 // it (but then be aware of the consequences of resulting resource leaks).
 //
-// See CloseAndWait, CloseAllAndWait, StartWorker, StartWorkers
+// See CloseAndWait, CloseAllAndWait, Partitioner, StartWorker, StartWorkers
 func ProcessBatch[Input any, Output any](
 
 	numTransformers int,
@@ -0,0 +1,67 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+)
+
+// Like ProcessBatch, but stop early when the given context is done. The
+// generate, transform and consume functions are each passed the context.
+// Once it is done, no further values are passed to transform or consume and
+// any values still sent by generate are discarded, so none of the goroutines
+// started by this function will block. Returns ctx.Err() after all of those
+// goroutines have exited.
+//
+// The generate function should check ctx and return promptly once it is done,
+// since this function cannot return until generate does. Similarly, transform
+// and consume should pass ctx along to any SDK function or API that accepts
+// one. For example, to impose a hard deadline on a batch:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+//	defer cancel()
+//	err := ProcessBatchContext(ctx, n, 1, 1, generate, transform, consume)
+//
+// See MakeCSVGeneratorContext, ProcessBatch, StartWorkerContext,
+// StartWorkersContext
+func ProcessBatchContext[Input any, Output any](
+
+	ctx context.Context,
+	numTransformers int,
+	transformersBufferSize int,
+	consumerBufferSize int,
+	generate func(context.Context, []chan<- Input),
+	transform func(context.Context, Input) Output,
+	consume func(context.Context, Output),
+
+) error {
+
+	func() {
+
+		// start a goroutine that will apply the consume function to each
+		// value sent to its channel until ctx is done
+		consumer, awaitConsumer := StartWorkerContext(ctx, consumerBufferSize, consume)
+		defer CloseAndWait(consumer, awaitConsumer)
+
+		// wrap the transform function in a closure that will send a given
+		// transformed input to the consumer channel unless ctx was done while
+		// transforming it
+		produce := func(ctx context.Context, request Input) {
+			output := transform(ctx, request)
+			if ctx.Err() == nil {
+				consumer <- output
+			}
+		}
+
+		// start n goroutines each of which will call the produce closure for
+		// each value sent to its channel until ctx is done
+		transformers, awaitTransformers := StartWorkersContext(ctx, numTransformers, transformersBufferSize, produce)
+		defer CloseAllAndWait(transformers, awaitTransformers)
+
+		// generate must send values of type Input to the channels it is
+		// passed, then return
+		generate(ctx, transformers)
+	}()
+
+	return ctx.Err()
+}
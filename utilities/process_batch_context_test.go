@@ -0,0 +1,66 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"errors"
+	"parasaurolophus/utilities"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestProcessBatchContext(t *testing.T) {
+	actual := []string{}
+	generate := func(ctx context.Context, transformers []chan<- int) {
+		n := len(transformers)
+		for i := range 10 {
+			transformers[i%n] <- i
+		}
+	}
+	transform := func(_ context.Context, input int) string {
+		return strconv.Itoa(input)
+	}
+	consume := func(_ context.Context, output string) {
+		actual = append(actual, output)
+	}
+	err := utilities.ProcessBatchContext(context.Background(), 3, 1, 1, generate, transform, consume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != 10 {
+		t.Errorf("expected 10, got %d", len(actual))
+	}
+}
+
+func TestProcessBatchContextTimeout(t *testing.T) {
+	generated := 0
+	actual := 0
+	generate := func(ctx context.Context, transformers []chan<- int) {
+		n := len(transformers)
+		for i := 0; ctx.Err() == nil; i++ {
+			transformers[i%n] <- i
+			generated++
+		}
+	}
+	transform := func(ctx context.Context, input int) int {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Millisecond):
+		}
+		return input
+	}
+	consume := func(_ context.Context, output int) {
+		actual++
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	err := utilities.ProcessBatchContext(ctx, 3, 1, 1, generate, transform, consume)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if actual >= generated {
+		t.Errorf("expected fewer than %d outputs, got %d", generated, actual)
+	}
+}
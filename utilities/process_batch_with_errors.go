@@ -0,0 +1,256 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"sync"
+)
+
+type (
+
+	// Parameters for ProcessBatchWithErrors.
+	//
+	// See ProcessBatchWithErrors
+	BatchOptions struct {
+
+		// Number of transformer goroutines.
+		NumTransformers int
+
+		// Size of each transformer goroutine's channel buffer, or of the
+		// shared one in SharedQueue mode.
+		TransformersBufferSize int
+
+		// Size of the consumer goroutine's channel buffer.
+		ConsumerBufferSize int
+
+		// Whether generate sends values to each transformer in turn or to a
+		// single queue shared by all of them.
+		Dispatch DispatchMode
+
+		// If not nil and Dispatch is RoundRobin, generate is passed a single
+		// channel and each value sent to it is routed to a transformer by
+		// applying a Partitioner to the result of passing it to Key, which
+		// must accept values of the batch's input type. Values with the same
+		// key are then handled by the same transformer in the order in which
+		// they were sent.
+		Key func(any) string
+
+		// Whether to stop on the first error or report all of them.
+		ErrorMode ErrorMode
+
+		// Whether to pass outputs to consume in the same order in which the
+		// corresponding inputs were sent by generate.
+		Ordered bool
+
+		// Maximum number of outputs held while waiting for earlier ones when
+		// Ordered is true. This also limits how far ahead of the oldest
+		// unfinished item transformers may run. Defaults to NumTransformers.
+		ReorderBufferSize int
+
+		// Whether a panic in transform or consume is reported as an error
+		// for the item being processed rather than terminating the process.
+		RecoverPanics bool
+
+		// If not nil, limits the rate and concurrency with which transform
+		// is invoked across all of the transformers.
+		Limiter *Limiter
+
+		// If not nil, is notified of the progress of the "transform" and
+		// "consume" stages.
+		Observer Observer
+	}
+)
+
+// Like ProcessBatchContext, but the generate, transform and consume functions
+// may each return an error. In FailFast mode, the first error cancels the
+// batch and is returned. In CollectAll mode, every item is processed and all
+// of the errors are returned, joined, in index order.
+//
+// Errors returned by transform or consume are wrapped in an ItemError whose
+// Index is the position of the failed item in the sequence of values sent by
+// generate, whichever transformer channel it was sent to. Items for which
+// transform returns an error are not passed to consume. If ctx is done before
+// the batch completes, its error is returned along with any others.
+//
+// In RoundRobin mode, the default, generate is passed one channel per
+// transformer as for ProcessBatch. Each transformer handles the values sent to
+// its own channel, in order, so one slow item delays the ones sent to the same
+// channel after it even if other transformers are idle. In SharedQueue mode,
+// generate is passed a single channel from which every transformer receives
+// values as soon as it is idle, which makes better use of the transformers
+// when items take varying amounts of time to process. If options.Key is not
+// nil, generate is passed a single channel in RoundRobin mode, too, but values
+// sent to it are routed to transformers by key, so that the items with a given
+// key are transformed one at a time in order, e.g. the rows for each device:
+//
+//	options := BatchOptions{
+//	  NumTransformers: 8,
+//	  Key: func(value any) string {
+//	    return value.(CSVTransformerParameters).Input["device_id"]
+//	  },
+//	}
+//	generate, err := MakeCSVGeneratorWithSchema(reader, headers, 1, schema, rejects.HandleError)
+//	...
+//	err = ProcessBatchWithErrors(ctx, options, generate, transform, consume)
+//
+// The values sent by generate are numbered in the order in which they were
+// sent, whether or not options.Ordered is true, which takes an extra goroutine
+// hop per item. When generate is passed more than one channel, i.e. in
+// RoundRobin mode with more than one transformer and no Key, each value must
+// also be received using reflect.Select, which costs noticeably more. Prefer
+// SharedQueue mode or Key when items are cheap to transform.
+//
+// Since transformers run concurrently, consume is passed outputs in no
+// particular order unless options.Ordered is true. In that case, outputs are
+// held in a reorder buffer until all of the ones before them have been
+// consumed (or have failed). Generation is paused as necessary to keep the
+// number of held outputs within options.ReorderBufferSize.
+//
+// If options.RecoverPanics is true, a panic in transform or consume is
+// reported as an ItemError wrapping a *PanicError.
+//
+// If options.Limiter is not nil, each transformer waits for it before invoking
+// transform, so as not to exceed its limits.
+//
+// If options.Observer is not nil, it is notified as each item is received and
+// finished by a transformer (as the "transform" stage) and by the consumer (as
+// the "consume" stage). Items for which transform fails are not received by
+// the consumer.
+//
+// See BatchOptions, ItemError, Limiter, MakeCSVConsumerWithErrors,
+// MakeCSVGeneratorWithErrors, Observer, PanicError, Partitioner,
+// ProcessBatchContext, StartSharedWorkersContext
+func ProcessBatchWithErrors[Input any, Output any](
+
+	ctx context.Context,
+	options BatchOptions,
+	generate func(context.Context, []chan<- Input) error,
+	transform func(context.Context, Input) (Output, error),
+	consume func(context.Context, Output) error,
+
+) error {
+
+	b := newBatch(ctx, options.ErrorMode)
+	defer b.cancel()
+
+	if options.RecoverPanics {
+		transform = recoverTransform(transform)
+		consume = recoverConsume(consume)
+	}
+
+	if options.Limiter != nil {
+		transform = limitTransform(options.Limiter, transform)
+	}
+
+	func() {
+
+		var (
+			consumer      chan<- indexed[Output]
+			awaitConsumer <-chan any
+			workers       []chan<- indexed[Input]
+			awaitWorkers  *sync.WaitGroup
+		)
+
+		consuming := stageObserver{
+			observer: options.Observer,
+			stage:    "consume",
+			depth:    func() int { return len(consumer) },
+		}
+
+		transforming := stageObserver{
+			observer: options.Observer,
+			stage:    "transform",
+			depth:    func() int { return queueDepth(workers) },
+		}
+
+		// start a goroutine that will apply the consume function to each
+		// value sent to its channel, recording any errors
+		consumeItem := func(ctx context.Context, output indexed[Output]) {
+			if output.skip {
+				return
+			}
+			received := consuming.received()
+			err := consume(ctx, output.value)
+			consuming.finishedItem(received, err)
+			if err != nil {
+				b.fail(&ItemError{Index: output.index, Err: err})
+			}
+		}
+
+		// in ordered mode, consume outputs only once all of the ones before
+		// them have been received and pause the sequencer as necessary to
+		// bound the number held in the meantime
+		var gate func(int)
+
+		if options.Ordered {
+			size := options.ReorderBufferSize
+			if size < 1 {
+				size = options.NumTransformers
+			}
+			reorder := newReorderBuffer[Output](b.ctx, size)
+			gate = func(index int) {
+				reorder.wait(b.ctx, index)
+			}
+			consumeInOrder := consumeItem
+			consumeItem = func(ctx context.Context, output indexed[Output]) {
+				reorder.add(output, func(output indexed[Output]) {
+					consumeInOrder(ctx, output)
+				})
+			}
+		}
+
+		consuming.started(1)
+		defer consuming.finished()
+		consumer, awaitConsumer = StartWorkerContext(b.ctx, options.ConsumerBufferSize, consumeItem)
+		defer CloseAndWait(consumer, awaitConsumer)
+
+		// start n goroutines each of which will apply the transform function
+		// to each value sent to its channel, sending the result to the
+		// consumer channel or recording the error
+		produce := func(ctx context.Context, input indexed[Input]) {
+			received := transforming.received()
+			output, err := transform(ctx, input.value)
+			transforming.finishedItem(received, err)
+			if err != nil {
+				b.fail(&ItemError{Index: input.index, Err: err})
+				consumer <- indexed[Output]{index: input.index, skip: true}
+				return
+			}
+			if ctx.Err() == nil {
+				consumer <- indexed[Output]{index: input.index, value: output}
+			}
+		}
+		transforming.started(options.NumTransformers)
+		defer transforming.finished()
+
+		switch options.Dispatch {
+
+		case SharedQueue:
+			var shared chan<- indexed[Input]
+			shared, awaitWorkers = StartSharedWorkersContext(b.ctx, options.NumTransformers, options.TransformersBufferSize, produce)
+			workers = []chan<- indexed[Input]{shared}
+
+		default:
+			workers, awaitWorkers = StartWorkersContext(b.ctx, options.NumTransformers, options.TransformersBufferSize, produce)
+		}
+
+		defer CloseAllAndWait(workers, awaitWorkers)
+
+		// tag each value sent by generate with its index before forwarding
+		// it to the corresponding transformer channel
+		transformers, awaitSequencer := sequence(workers, gate, keyRoute[Input](options, len(workers)))
+		defer func() {
+			for _, t := range transformers {
+				close(t)
+			}
+			<-awaitSequencer
+		}()
+
+		if err := generate(b.ctx, transformers); err != nil {
+			b.fail(err)
+		}
+	}()
+
+	return b.err(ctx)
+}
@@ -0,0 +1,252 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"parasaurolophus/utilities"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProcessBatchWithErrors(t *testing.T) {
+	actual := 0
+	options := utilities.BatchOptions{
+		NumTransformers:        3,
+		TransformersBufferSize: 1,
+		ConsumerBufferSize:     1,
+	}
+	generate := func(_ context.Context, transformers []chan<- int) error {
+		n := len(transformers)
+		for i := range 10 {
+			transformers[i%n] <- i
+		}
+		return nil
+	}
+	transform := func(_ context.Context, input int) (int, error) {
+		return input * 2, nil
+	}
+	consume := func(_ context.Context, output int) error {
+		actual += output
+		return nil
+	}
+	err := utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != 90 {
+		t.Errorf("expected 90, got %d", actual)
+	}
+}
+
+func TestProcessBatchWithErrorsFailFast(t *testing.T) {
+	generated := 0
+	options := utilities.BatchOptions{
+		NumTransformers: 3,
+		ErrorMode:       utilities.FailFast,
+	}
+	generate := func(ctx context.Context, transformers []chan<- int) error {
+		n := len(transformers)
+		for i := 0; ctx.Err() == nil; i++ {
+			transformers[i%n] <- i
+			generated++
+		}
+		return nil
+	}
+	transform := func(_ context.Context, input int) (int, error) {
+		if input == 5 {
+			return 0, fmt.Errorf("five")
+		}
+		return input, nil
+	}
+	consume := func(context.Context, int) error {
+		return nil
+	}
+	err := utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume)
+	var itemError *utilities.ItemError
+	if !errors.As(err, &itemError) {
+		t.Fatalf("expected an ItemError, got %v", err)
+	}
+	if itemError.Index != 5 {
+		t.Errorf("expected index 5, got %d", itemError.Index)
+	}
+	if generated < 6 {
+		t.Errorf("expected at least 6 items to be generated, got %d", generated)
+	}
+}
+
+func TestProcessBatchWithErrorsCollectAll(t *testing.T) {
+	actual := 0
+	lock := sync.Mutex{}
+	options := utilities.BatchOptions{
+		NumTransformers: 3,
+		ErrorMode:       utilities.CollectAll,
+	}
+	generate := func(_ context.Context, transformers []chan<- int) error {
+		// deliberately not round-robin, to show that indices reflect the
+		// order in which items were sent
+		for i := range 10 {
+			transformers[0] <- i
+		}
+		return nil
+	}
+	transform := func(_ context.Context, input int) (int, error) {
+		if input%3 == 0 {
+			return 0, fmt.Errorf("%d is divisible by 3", input)
+		}
+		return input, nil
+	}
+	consume := func(_ context.Context, output int) error {
+		defer lock.Unlock()
+		lock.Lock()
+		if output == 7 {
+			return fmt.Errorf("unlucky")
+		}
+		actual += output
+		return nil
+	}
+	err := utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got %v", err)
+	}
+	indices := []int{}
+	for _, e := range joined.Unwrap() {
+		var itemError *utilities.ItemError
+		if !errors.As(e, &itemError) {
+			t.Fatalf("expected an ItemError, got %v", e)
+		}
+		indices = append(indices, itemError.Index)
+	}
+	if fmt.Sprint(indices) != "[0 3 6 7 9]" {
+		t.Errorf("expected [0 3 6 7 9], got %v", indices)
+	}
+	if actual != 1+2+4+5+8 {
+		t.Errorf("expected %d, got %d", 1+2+4+5+8, actual)
+	}
+}
+
+func TestProcessBatchWithErrorsOrdered(t *testing.T) {
+	actual := []int{}
+	options := utilities.BatchOptions{
+		NumTransformers:   4,
+		ErrorMode:         utilities.CollectAll,
+		Ordered:           true,
+		ReorderBufferSize: 8,
+	}
+	generate := func(_ context.Context, transformers []chan<- int) error {
+		n := len(transformers)
+		for i := range 100 {
+			transformers[i%n] <- i
+		}
+		return nil
+	}
+	transform := func(_ context.Context, input int) (int, error) {
+		time.Sleep(time.Duration(rand.IntN(500)) * time.Microsecond)
+		if input%10 == 9 {
+			return 0, fmt.Errorf("skipping %d", input)
+		}
+		return input, nil
+	}
+	consume := func(_ context.Context, output int) error {
+		actual = append(actual, output)
+		return nil
+	}
+	err := utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume)
+	if err == nil {
+		t.Error("expected an error")
+	}
+	if len(actual) != 90 {
+		t.Errorf("expected 90, got %d", len(actual))
+	}
+	if !slices.IsSorted(actual) {
+		t.Errorf("expected outputs in order, got %v", actual)
+	}
+}
+
+func TestProcessBatchWithErrorsSharedQueue(t *testing.T) {
+	actual := []int{}
+	options := utilities.BatchOptions{
+		NumTransformers: 2,
+		Dispatch:        utilities.SharedQueue,
+	}
+	generate := func(_ context.Context, transformers []chan<- int) error {
+		if len(transformers) != 1 {
+			return fmt.Errorf("expected 1 channel, got %d", len(transformers))
+		}
+		for i := range 10 {
+			transformers[0] <- i
+		}
+		return nil
+	}
+	transform := func(_ context.Context, input int) (int, error) {
+		if input == 0 {
+			time.Sleep(time.Millisecond * 50)
+		}
+		return input, nil
+	}
+	consume := func(_ context.Context, output int) error {
+		actual = append(actual, output)
+		return nil
+	}
+	err := utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != 10 {
+		t.Fatalf("expected 10, got %d", len(actual))
+	}
+	if actual[9] != 0 {
+		t.Errorf("expected slow item to be consumed last, got %v", actual)
+	}
+}
+
+func TestProcessBatchWithErrorsKey(t *testing.T) {
+	actual := map[int][]int{}
+	lock := sync.Mutex{}
+	options := utilities.BatchOptions{
+		NumTransformers: 3,
+		Key: func(value any) string {
+			return fmt.Sprint(value.(int) % 7)
+		},
+	}
+	generate := func(_ context.Context, transformers []chan<- int) error {
+		if len(transformers) != 1 {
+			return fmt.Errorf("expected 1 channel, got %d", len(transformers))
+		}
+		for i := range 100 {
+			transformers[0] <- i
+		}
+		return nil
+	}
+	transform := func(_ context.Context, input int) (int, error) {
+		time.Sleep(time.Duration(rand.IntN(100)) * time.Microsecond)
+		defer lock.Unlock()
+		lock.Lock()
+		actual[input%7] = append(actual[input%7], input)
+		return input, nil
+	}
+	consume := func(context.Context, int) error {
+		return nil
+	}
+	err := utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != 7 {
+		t.Fatalf("expected 7 keys, got %d", len(actual))
+	}
+	for key, inputs := range actual {
+		if !slices.IsSorted(inputs) {
+			t.Errorf("expected inputs for key %d in order, got %v", key, inputs)
+		}
+	}
+}
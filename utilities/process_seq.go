@@ -0,0 +1,176 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+)
+
+// Return a function for use as the generate parameter to ProcessBatchContext
+// or ProcessBatchWithErrors which sends each of the given inputs to the
+// batch's transformers in round-robin fashion, stopping early if its context
+// is done.
+//
+// See ProcessBatchContext, ProcessBatchWithErrors, ProcessSeq
+func SeqGenerator[Input any](inputs iter.Seq[Input]) func(context.Context, []chan<- Input) error {
+
+	return func(ctx context.Context, transformers []chan<- Input) error {
+		n := len(transformers)
+		i := 0
+		for input := range inputs {
+			select {
+			case transformers[i%n] <- input:
+			case <-ctx.Done():
+				return nil
+			}
+			i++
+		}
+		return nil
+	}
+}
+
+// Like ProcessBatchWithErrors, but take the inputs from the given sequence and
+// return the outputs as a sequence rather than passing them to a consume
+// function, so that batches compose with range-over-func. For example:
+//
+//	outputs, err := ProcessSeq(ctx, options, slices.Values(inputs), transform)
+//	for output := range outputs {
+//	  ...
+//	}
+//	if err() != nil {
+//	  ...
+//	}
+//
+// The batch runs each time outputs is ranged over, in a goroutine of its own,
+// and err returns the error with which the most recent run completed once the
+// loop has finished. Outputs are yielded as they are ready, so they are in the
+// order of the corresponding inputs only if options.Ordered is true. Breaking
+// out of the loop early cancels the batch, in which case err reports
+// context.Canceled.
+//
+// See ProcessBatchWithErrors, ProcessSeq2, SeqGenerator
+func ProcessSeq[Input any, Output any](
+
+	ctx context.Context,
+	options BatchOptions,
+	inputs iter.Seq[Input],
+	transform func(context.Context, Input) (Output, error),
+
+) (
+
+	outputs iter.Seq[Output],
+	err func() error,
+
+) {
+
+	return ProcessSeq2(
+		ctx,
+		options,
+		func(yield func(Input, error) bool) {
+			for input := range inputs {
+				if !yield(input, nil) {
+					return
+				}
+			}
+		},
+		transform,
+	)
+}
+
+// Like ProcessSeq, but for a sequence which yields each input along with an
+// error, such as the one returned by MakeCSVSeq. Inputs yielded with a non-nil
+// error are not processed. In FailFast mode, the first such error stops the
+// batch. In CollectAll mode, they are returned by err along with any others.
+//
+// See MakeCSVSeq, ProcessSeq
+func ProcessSeq2[Input any, Output any](
+
+	ctx context.Context,
+	options BatchOptions,
+	inputs iter.Seq2[Input, error],
+	transform func(context.Context, Input) (Output, error),
+
+) (
+
+	outputs iter.Seq[Output],
+	err func() error,
+
+) {
+
+	var (
+		lock sync.Mutex
+		e    error
+	)
+
+	err = func() error {
+		defer lock.Unlock()
+		lock.Lock()
+		return e
+	}
+
+	outputs = func(yield func(Output) bool) {
+
+		run, stop := context.WithCancel(ctx)
+		defer stop()
+
+		// errors yielded by inputs in CollectAll mode
+		var inputErrs []error
+
+		generate := func(ctx context.Context, transformers []chan<- Input) error {
+			n := len(transformers)
+			i := 0
+			for input, err := range inputs {
+				if err != nil {
+					if options.ErrorMode == FailFast {
+						return err
+					}
+					inputErrs = append(inputErrs, err)
+					continue
+				}
+				select {
+				case transformers[i%n] <- input:
+				case <-ctx.Done():
+					return nil
+				}
+				i++
+			}
+			return nil
+		}
+
+		values := make(chan Output)
+
+		consume := func(ctx context.Context, output Output) error {
+			select {
+			case values <- output:
+			case <-ctx.Done():
+			}
+			return nil
+		}
+
+		go func() {
+			defer close(values)
+			err := ProcessBatchWithErrors(run, options, generate, transform, consume)
+			if len(inputErrs) > 0 {
+				err = errors.Join(append(inputErrs, err)...)
+			}
+			defer lock.Unlock()
+			lock.Lock()
+			e = err
+		}()
+
+		for value := range values {
+			if !yield(value) {
+				// let the batch shut down before returning
+				stop()
+				for range values {
+				}
+				return
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"errors"
+	"parasaurolophus/utilities"
+	"slices"
+	"testing"
+)
+
+func TestProcessSeqOrdered(t *testing.T) {
+	options := utilities.BatchOptions{NumTransformers: 4, Ordered: true}
+	square := func(_ context.Context, n int) (int, error) {
+		return n * n, nil
+	}
+	outputs, err := utilities.ProcessSeq(context.Background(), options, slices.Values([]int{1, 2, 3, 4, 5}), square)
+	if actual := slices.Collect(outputs); !slices.Equal(actual, []int{1, 4, 9, 16, 25}) {
+		t.Errorf("unexpected %v", actual)
+	}
+	if err() != nil {
+		t.Error(err())
+	}
+}
+
+func TestProcessSeqBreak(t *testing.T) {
+	options := utilities.BatchOptions{NumTransformers: 2, Ordered: true}
+	identity := func(_ context.Context, n int) (int, error) {
+		return n, nil
+	}
+	naturals := func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	}
+	outputs, err := utilities.ProcessSeq(context.Background(), options, naturals, identity)
+	actual := []int{}
+	for output := range outputs {
+		if output == 3 {
+			break
+		}
+		actual = append(actual, output)
+	}
+	if !slices.Equal(actual, []int{0, 1, 2}) {
+		t.Errorf("unexpected %v", actual)
+	}
+	if !errors.Is(err(), context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err())
+	}
+}
+
+func TestProcessSeq2Errors(t *testing.T) {
+	inputs := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errors.New("bad input")) && yield(2, nil)
+	}
+	identity := func(_ context.Context, n int) (int, error) {
+		return n, nil
+	}
+	options := utilities.BatchOptions{NumTransformers: 1, ErrorMode: utilities.CollectAll}
+	outputs, err := utilities.ProcessSeq2(context.Background(), options, inputs, identity)
+	if actual := slices.Collect(outputs); !slices.Equal(actual, []int{1, 2}) {
+		t.Errorf("unexpected %v", actual)
+	}
+	if err() == nil || err().Error() != "bad input" {
+		t.Errorf("expected bad input, got %v", err())
+	}
+}
+
+func TestSeqGenerator(t *testing.T) {
+	options := utilities.BatchOptions{NumTransformers: 3}
+	total := 0
+	err := utilities.ProcessBatchWithErrors(
+		context.Background(),
+		options,
+		utilities.SeqGenerator(slices.Values([]int{1, 2, 3, 4})),
+		func(_ context.Context, n int) (int, error) { return n * 10, nil },
+		func(_ context.Context, n int) error { total += n; return nil },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 100 {
+		t.Errorf("expected 100, got %d", total)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+type (
+
+	// Error reported when a handler or transform function panics. Value is the
+	// value passed to panic, Item is the value being handled at the time and
+	// Stack is the stack trace of the goroutine that panicked.
+	//
+	// See RecoverPanics, RecoverPanicsContext
+	PanicError struct {
+		Value any
+		Item  any
+		Stack []byte
+	}
+)
+
+func (err *PanicError) Error() string {
+
+	return fmt.Sprintf("panic handling %v: %v", err.Item, err.Value)
+}
+
+// Return the value passed to panic, if it was an error.
+func (err *PanicError) Unwrap() error {
+
+	if e, ok := err.Value.(error); ok {
+		return e
+	}
+
+	return nil
+}
+
+// Return a handler which invokes the given one, passing a *PanicError to the
+// given errorHandler if it panics rather than allowing the panic to terminate
+// the process. A worker goroutine started using the returned handler keeps
+// running after such a panic, continuing with the next value sent to it. For
+// example:
+//
+//	{
+//	  values, await := StartWorker(bufferSize, RecoverPanics(handler, errorHandler))
+//	  defer CloseAndWait(values, await)
+//	  for _, value := range data {
+//	    values <- value
+//	  }
+//	}
+//
+// See PanicError, RecoverPanicsContext, StartWorker, StartWorkers
+func RecoverPanics[V any](
+
+	handler func(V),
+	errorHandler func(error),
+
+) func(V) {
+
+	return func(value V) {
+		if err := catchPanic(value, func() { handler(value) }); err != nil {
+			errorHandler(err)
+		}
+	}
+}
+
+// Like RecoverPanics, but for handlers that are passed a context.
+//
+// See PanicError, RecoverPanics, StartWorkerContext, StartWorkersContext
+func RecoverPanicsContext[V any](
+
+	handler func(context.Context, V),
+	errorHandler func(error),
+
+) func(context.Context, V) {
+
+	return func(ctx context.Context, value V) {
+		if err := catchPanic(value, func() { handler(ctx, value) }); err != nil {
+			errorHandler(err)
+		}
+	}
+}
+
+// Invoke fn, returning a *PanicError for the given item if it panics.
+func catchPanic(item any, fn func()) (err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Item: item, Stack: debug.Stack()}
+		}
+	}()
+
+	fn()
+	return
+}
+
+// Return a function which invokes the given transform function, returning a
+// *PanicError if it panics.
+func recoverTransform[Input any, Output any](
+
+	transform func(context.Context, Input) (Output, error),
+
+) func(context.Context, Input) (Output, error) {
+
+	return func(ctx context.Context, input Input) (output Output, err error) {
+		if e := catchPanic(input, func() { output, err = transform(ctx, input) }); e != nil {
+			err = e
+		}
+		return
+	}
+}
+
+// Return a function which invokes the given consume function, returning a
+// *PanicError if it panics.
+func recoverConsume[Output any](
+
+	consume func(context.Context, Output) error,
+
+) func(context.Context, Output) error {
+
+	return func(ctx context.Context, output Output) (err error) {
+		if e := catchPanic(output, func() { err = consume(ctx, output) }); e != nil {
+			err = e
+		}
+		return
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"errors"
+	"parasaurolophus/utilities"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRecoverPanics(t *testing.T) {
+	actual := 0
+	panics := []*utilities.PanicError{}
+	handler := func(n int) {
+		if n%3 == 0 {
+			panic("divisible by 3")
+		}
+		actual += n
+	}
+	errorHandler := func(err error) {
+		var panicError *utilities.PanicError
+		if errors.As(err, &panicError) {
+			panics = append(panics, panicError)
+		}
+	}
+	func() {
+		values, await := utilities.StartWorker(1, utilities.RecoverPanics(handler, errorHandler))
+		defer utilities.CloseAndWait(values, await)
+		for i := range 10 {
+			values <- i
+		}
+	}()
+	if actual != 1+2+4+5+7+8 {
+		t.Errorf("expected %d, got %d", 1+2+4+5+7+8, actual)
+	}
+	if len(panics) != 4 {
+		t.Fatalf("expected 4 panics, got %d", len(panics))
+	}
+	if panics[1].Item != 3 || panics[1].Value != "divisible by 3" {
+		t.Errorf("unexpected panic %v", panics[1])
+	}
+	if !strings.Contains(string(panics[1].Stack), "TestRecoverPanics") {
+		t.Errorf("expected stack trace to include test function, got %s", panics[1].Stack)
+	}
+}
+
+func TestRecoverPanicsContext(t *testing.T) {
+	count := 0
+	lock := sync.Mutex{}
+	handler := func(_ context.Context, n int) {
+		if n == 5 {
+			var m map[string]int
+			m["boom"] = n
+		}
+	}
+	errorHandler := func(err error) {
+		defer lock.Unlock()
+		lock.Lock()
+		count++
+	}
+	func() {
+		values, await := utilities.StartWorkersContext(context.Background(), 3, 1, utilities.RecoverPanicsContext(handler, errorHandler))
+		defer utilities.CloseAllAndWait(values, await)
+		for i := range 10 {
+			values[i%3] <- i
+		}
+	}()
+	if count != 1 {
+		t.Errorf("expected 1 panic, got %d", count)
+	}
+}
+
+func TestProcessBatchWithErrorsRecoverPanics(t *testing.T) {
+	options := utilities.BatchOptions{
+		NumTransformers: 2,
+		ErrorMode:       utilities.CollectAll,
+		RecoverPanics:   true,
+	}
+	generate := func(_ context.Context, transformers []chan<- int) error {
+		for i := range 10 {
+			transformers[i%2] <- i
+		}
+		return nil
+	}
+	transform := func(_ context.Context, input int) (int, error) {
+		if input == 4 {
+			panic(errors.New("four"))
+		}
+		return input, nil
+	}
+	consume := func(context.Context, int) error {
+		return nil
+	}
+	err := utilities.ProcessBatchWithErrors(context.Background(), options, generate, transform, consume)
+	var itemError *utilities.ItemError
+	if !errors.As(err, &itemError) || itemError.Index != 4 {
+		t.Fatalf("expected an ItemError for index 4, got %v", err)
+	}
+	var panicError *utilities.PanicError
+	if !errors.As(err, &panicError) {
+		t.Errorf("expected a PanicError, got %v", err)
+	}
+	if err.Error() != "item 4: panic handling 4: four" {
+		t.Errorf(`unexpected error message "%s"`, err.Error())
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Like StartWorkers, but report the progress of the workers to the given
+// observer as the given stage. The stage is reported as finished once all of
+// the workers have exited.
+//
+// See Observer, StartWorkers, SummaryObserver
+func StartObservedWorkers[V any](
+
+	observer Observer,
+	stage string,
+	numWorkers int,
+	bufferSize int,
+	handler func(V),
+
+) (
+
+	values []chan<- V,
+	await *sync.WaitGroup,
+
+) {
+
+	v := make([]chan V, numWorkers)
+	values = make([]chan<- V, numWorkers)
+	await = &sync.WaitGroup{}
+	await.Add(numWorkers)
+	s := stageObserver{
+		observer: observer,
+		stage:    stage,
+		depth:    func() int { return queueDepth(values) },
+	}
+	s.started(numWorkers)
+	running := atomic.Int32{}
+	running.Store(int32(numWorkers))
+	for i := range numWorkers {
+		v[i] = make(chan V, bufferSize)
+		values[i] = v[i]
+		go func() {
+			defer await.Done()
+			defer func() {
+				if running.Add(-1) == 0 {
+					s.finished()
+				}
+			}()
+			for value := range v[i] {
+				received := s.received()
+				handler(value)
+				s.finishedItem(received, nil)
+			}
+		}()
+	}
+	return
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"parasaurolophus/utilities"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStartObservedWorkers(t *testing.T) {
+	summary := &utilities.SummaryObserver{}
+	count := atomic.Int32{}
+	handler := func(n int) {
+		count.Add(int32(n))
+	}
+	func() {
+		values, await := utilities.StartObservedWorkers(summary, "sum", 3, 10, handler)
+		defer utilities.CloseAllAndWait(values, await)
+		for i := range 10 {
+			values[i%len(values)] <- i
+		}
+	}()
+	if count.Load() != 45 {
+		t.Errorf("expected 45, got %d", count.Load())
+	}
+	stages := summary.Summary()
+	if len(stages) != 1 {
+		t.Fatalf("expected 1 stage, got %d", len(stages))
+	}
+	if s := stages[0]; s.Stage != "sum" || s.NumWorkers != 3 || s.ItemsIn != 10 || s.ItemsOut != 10 || s.MaxQueueDepth > 9 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+}
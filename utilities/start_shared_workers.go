@@ -0,0 +1,50 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"sync"
+)
+
+// Like StartWorkers, but all of the goroutines receive from a single shared
+// values channel. Each value is handled by whichever goroutine is idle first,
+// so a value that takes a long time to handle does not delay the ones sent
+// after it, as it would if they were sent to the same one of the channels
+// returned by StartWorkers. For example:
+//
+//	{
+//	  values, await := StartSharedWorkers(numWorkers, bufferSize, handler)
+//	  defer CloseAllAndWait([]chan<- V{values}, await)
+//	  for _, value := range data {
+//	    values <- value
+//	  }
+//	}
+//
+// See CloseAllAndWait, StartSharedWorkersContext, StartWorkers
+func StartSharedWorkers[V any](
+
+	numWorkers int,
+	bufferSize int,
+	handler func(V),
+
+) (
+
+	values chan<- V,
+	await *sync.WaitGroup,
+
+) {
+
+	v := make(chan V, bufferSize)
+	values = v
+	await = &sync.WaitGroup{}
+	await.Add(numWorkers)
+	for range numWorkers {
+		go func() {
+			defer await.Done()
+			for value := range v {
+				handler(value)
+			}
+		}()
+	}
+	return
+}
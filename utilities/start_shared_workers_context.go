@@ -0,0 +1,41 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"sync"
+)
+
+// Like StartSharedWorkers, but the handler is passed the given context and
+// stops being invoked once the context is done. Values sent to the returned
+// values channel after that are received and discarded, so that senders are
+// never blocked, until the channel is closed.
+//
+// See CloseAllAndWait, StartSharedWorkers, StartWorkersContext
+func StartSharedWorkersContext[V any](
+
+	ctx context.Context,
+	numWorkers int,
+	bufferSize int,
+	handler func(context.Context, V),
+
+) (
+
+	values chan<- V,
+	await *sync.WaitGroup,
+
+) {
+
+	v := make(chan V, bufferSize)
+	values = v
+	await = &sync.WaitGroup{}
+	await.Add(numWorkers)
+	for range numWorkers {
+		go func() {
+			defer await.Done()
+			work(ctx, v, handler)
+		}()
+	}
+	return
+}
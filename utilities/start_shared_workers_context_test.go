@@ -0,0 +1,37 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"parasaurolophus/utilities"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartSharedWorkersContext(t *testing.T) {
+	actual := []int{}
+	lock := sync.Mutex{}
+	handler := func(_ context.Context, n int) {
+		if n == 0 {
+			time.Sleep(time.Millisecond * 50)
+		}
+		defer lock.Unlock()
+		lock.Lock()
+		actual = append(actual, n)
+	}
+	func() {
+		values, await := utilities.StartSharedWorkersContext(context.Background(), 2, 0, handler)
+		defer utilities.CloseAllAndWait([]chan<- int{values}, await)
+		for i := range 10 {
+			values <- i
+		}
+	}()
+	if len(actual) != 10 {
+		t.Fatalf("expected 10, got %d", len(actual))
+	}
+	if actual[9] != 0 {
+		t.Errorf("expected slow value to be handled last, got %v", actual)
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"parasaurolophus/utilities"
+	"sync"
+	"testing"
+)
+
+func TestStartSharedWorkers(t *testing.T) {
+	actual := 0
+	lock := sync.Mutex{}
+	handler := func(n int) {
+		defer lock.Unlock()
+		lock.Lock()
+		actual += n
+	}
+	func() {
+		values, await := utilities.StartSharedWorkers(3, 1, handler)
+		defer utilities.CloseAllAndWait([]chan<- int{values}, await)
+		for i := range 10 {
+			values <- i
+		}
+	}()
+	if actual != 45 {
+		t.Errorf("expected 45, got %d", actual)
+	}
+}
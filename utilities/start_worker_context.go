@@ -0,0 +1,60 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+)
+
+// Like StartWorker, but the handler is passed the given context and stops
+// being invoked once the context is done. Values sent to the returned values
+// channel after that are received and discarded, so that senders are never
+// blocked, until the channel is closed. For example:
+//
+//	{
+//	  values, await := StartWorkerContext(ctx, bufferSize, handler)
+//	  defer CloseAndWait(values, await)
+//	  for _, value := range data {
+//	    if ctx.Err() != nil {
+//	      break
+//	    }
+//	    values <- value
+//	  }
+//	}
+//
+// See CloseAndWait, StartWorker, StartWorkersContext
+func StartWorkerContext[V any](
+
+	ctx context.Context,
+	bufferSize int,
+	handler func(context.Context, V),
+
+) (
+
+	values chan<- V,
+	await <-chan any,
+
+) {
+
+	v := make(chan V, bufferSize)
+	values = v
+	a := make(chan any)
+	await = a
+	go func() {
+		defer close(a)
+		work(ctx, v, handler)
+	}()
+	return
+}
+
+// Invoke handler for each value received from the given channel until it is
+// closed, discarding rather than handling values received after ctx is done.
+func work[V any](ctx context.Context, values <-chan V, handler func(context.Context, V)) {
+
+	for value := range values {
+		if ctx.Err() != nil {
+			continue
+		}
+		handler(ctx, value)
+	}
+}
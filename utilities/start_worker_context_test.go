@@ -0,0 +1,48 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"parasaurolophus/utilities"
+	"testing"
+)
+
+func TestStartWorkerContext(t *testing.T) {
+	actual := 0
+	handler := func(_ context.Context, n int) {
+		actual += n
+	}
+	func() {
+		values, await := utilities.StartWorkerContext(context.Background(), 1, handler)
+		defer utilities.CloseAndWait(values, await)
+		for i := range 10 {
+			values <- i
+		}
+	}()
+	if actual != 45 {
+		t.Errorf("expected 45, got %d", actual)
+	}
+}
+
+func TestStartWorkerContextCanceled(t *testing.T) {
+	actual := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := func(_ context.Context, n int) {
+		actual += n
+		if n == 4 {
+			cancel()
+		}
+	}
+	func() {
+		values, await := utilities.StartWorkerContext(ctx, 0, handler)
+		defer utilities.CloseAndWait(values, await)
+		for i := range 10 {
+			values <- i
+		}
+	}()
+	if actual != 10 {
+		t.Errorf("expected 10, got %d", actual)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"sync"
+)
+
+// Like StartWorkers, but the handler is passed the given context and stops
+// being invoked once the context is done. Values sent to any of the returned
+// values channels after that are received and discarded, so that senders are
+// never blocked, until the channels are closed. For example:
+//
+//	{
+//	  values, await := StartWorkersContext(ctx, numWorkers, bufferSize, handler)
+//	  defer CloseAllAndWait(values, await)
+//	  for i, value := range data {
+//	    if ctx.Err() != nil {
+//	      break
+//	    }
+//	    values[i%numWorkers] <- value
+//	  }
+//	}
+//
+// See CloseAllAndWait, StartWorkerContext, StartWorkers
+func StartWorkersContext[V any](
+
+	ctx context.Context,
+	numWorkers int,
+	bufferSize int,
+	handler func(context.Context, V),
+
+) (
+
+	values []chan<- V,
+	await *sync.WaitGroup,
+
+) {
+
+	v := make([]chan V, numWorkers)
+	values = make([]chan<- V, numWorkers)
+	await = &sync.WaitGroup{}
+	await.Add(numWorkers)
+	for i := range numWorkers {
+		v[i] = make(chan V, bufferSize)
+		values[i] = v[i]
+		go func() {
+			defer await.Done()
+			work(ctx, v[i], handler)
+		}()
+	}
+	return
+}
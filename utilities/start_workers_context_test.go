@@ -0,0 +1,52 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"parasaurolophus/utilities"
+	"sync"
+	"testing"
+)
+
+func TestStartWorkersContext(t *testing.T) {
+	actual := 0
+	lock := sync.Mutex{}
+	handler := func(_ context.Context, n int) {
+		defer lock.Unlock()
+		lock.Lock()
+		actual += n
+	}
+	func() {
+		values, await := utilities.StartWorkersContext(context.Background(), 3, 1, handler)
+		defer utilities.CloseAllAndWait(values, await)
+		for i := range len(values) {
+			values[i] <- i
+		}
+	}()
+	if actual != 3 {
+		t.Errorf("expected 3, got %d", actual)
+	}
+}
+
+func TestStartWorkersContextCanceled(t *testing.T) {
+	actual := 0
+	lock := sync.Mutex{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	handler := func(_ context.Context, n int) {
+		defer lock.Unlock()
+		lock.Lock()
+		actual += n
+	}
+	func() {
+		values, await := utilities.StartWorkersContext(ctx, 3, 0, handler)
+		defer utilities.CloseAllAndWait(values, await)
+		for i := range 30 {
+			values[i%len(values)] <- i
+		}
+	}()
+	if actual != 0 {
+		t.Errorf("expected 0, got %d", actual)
+	}
+}
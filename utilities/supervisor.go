@@ -0,0 +1,467 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+
+	// Whether a supervised child is restarted when it exits.
+	//
+	// See ChildSpec
+	RestartPolicy int
+
+	// Which children a Supervisor restarts when one of them exits.
+	//
+	// See SupervisorOptions
+	RestartStrategy int
+
+	// Lifecycle state of a supervised child.
+	//
+	// See ChildStatus
+	ChildState int
+
+	// Specification of a child goroutine started by a Supervisor. Start
+	// should run until its context is done, returning nil, or until it fails,
+	// returning an error. A panic in Start is treated as a failure with a
+	// *PanicError.
+	//
+	// See StartSupervisor
+	ChildSpec struct {
+		Name    string
+		Start   func(context.Context) error
+		Restart RestartPolicy
+	}
+
+	// Snapshot of the state of a supervised child.
+	//
+	// See Supervisor
+	ChildStatus struct {
+		Name      string
+		State     ChildState
+		Restarts  int
+		LastError error
+		Since     time.Time
+	}
+
+	// Parameters for StartSupervisor.
+	//
+	// See StartSupervisor
+	SupervisorOptions struct {
+
+		// Which children to restart when one of them exits.
+		Strategy RestartStrategy
+
+		// Maximum number of restarts allowed within Period, after which the
+		// supervisor stops all of its children and gives up. Restarts are
+		// not limited if either is zero.
+		MaxRestarts int
+		Period      time.Duration
+
+		// Delay before restarting a child, doubled for each consecutive
+		// restart of the same child up to MaxBackoff. A child's delay is
+		// reset once it has run for at least Period or, if Period is zero,
+		// for at least MaxBackoff. The delay is never reset if both are
+		// zero.
+		MinBackoff time.Duration
+		MaxBackoff time.Duration
+	}
+
+	// Handle to the goroutine started by StartSupervisor.
+	//
+	// See StartSupervisor
+	Supervisor struct {
+		lock     sync.Mutex
+		children []ChildStatus
+		err      error
+	}
+
+	// Bookkeeping for a supervised child.
+	supervisedChild struct {
+		spec     ChildSpec
+		state    ChildState
+		cancel   context.CancelFunc
+		running  bool
+		stopping bool
+		started  time.Time
+		failures int
+	}
+
+	// Notification that a supervised child has exited.
+	childExit struct {
+		child int
+		err   error
+	}
+)
+
+const (
+
+	// Always restart the child when it exits.
+	Permanent = RestartPolicy(iota)
+
+	// Restart the child only when it exits with an error.
+	Transient
+
+	// Never restart the child.
+	Temporary
+)
+
+const (
+
+	// Restart only the child that exited.
+	OneForOne = RestartStrategy(iota)
+
+	// Stop all of the other children and restart all of them together,
+	// except for Temporary children, which are stopped but not restarted.
+	// Whether to do so depends only on the exit of the child that caused
+	// it, so a Transient child stopped this way is restarted even though
+	// it returns nil.
+	OneForAll
+)
+
+const (
+
+	// The child is running.
+	ChildRunning = ChildState(iota)
+
+	// The child has exited and will be restarted.
+	ChildRestarting
+
+	// The child has exited and will not be restarted.
+	ChildStopped
+)
+
+func (state ChildState) String() string {
+
+	switch state {
+	case ChildRunning:
+		return "running"
+	case ChildRestarting:
+		return "restarting"
+	case ChildStopped:
+		return "stopped"
+	default:
+		return fmt.Sprintf("ChildState(%d)", int(state))
+	}
+}
+
+// Start a goroutine which starts each of the given children in a goroutine of
+// its own and restarts them as they exit according to their restart policies
+// and the given options. This allows a long-running process to recover from
+// the failure of one of its workers, e.g. a dropped connection, without
+// stopping altogether. For example:
+//
+//	children := []ChildSpec{
+//	  {Name: "ground floor", Start: subscribe(groundFloorBridge)},
+//	  {Name: "basement", Start: subscribe(basementBridge)},
+//	}
+//	options := SupervisorOptions{MaxRestarts: 5, Period: time.Minute, MinBackoff: time.Second, MaxBackoff: time.Minute}
+//	supervisor, terminate, await := StartSupervisor(options, children)
+//	defer CloseAndWait(terminate, await)
+//
+// Closing the terminate channel causes the supervisor to cancel the contexts
+// of all of its children and wait for them to exit. The await channel is
+// closed once the supervisor has exited, either because terminate was closed,
+// because none of its children remain to be restarted or because the restart
+// intensity specified by options was exceeded, in which case Err will return
+// a non-nil value.
+//
+// See ChildSpec, CloseAndWait, Supervisor, SupervisorOptions
+func StartSupervisor(
+
+	options SupervisorOptions,
+	children []ChildSpec,
+
+) (
+
+	supervisor *Supervisor,
+	terminate chan<- any,
+	await <-chan any,
+
+) {
+
+	term := make(chan any)
+	aw := make(chan any)
+	terminate = term
+	await = aw
+
+	supervisor = &Supervisor{children: make([]ChildStatus, len(children))}
+
+	for i, spec := range children {
+		supervisor.children[i].Name = spec.Name
+	}
+
+	go supervisor.run(options, children, term, aw)
+
+	return
+}
+
+// Return a snapshot of the status of each of the supervisor's children, in
+// the order they were specified.
+func (supervisor *Supervisor) Status() []ChildStatus {
+
+	defer supervisor.lock.Unlock()
+	supervisor.lock.Lock()
+
+	status := make([]ChildStatus, len(supervisor.children))
+	copy(status, supervisor.children)
+	return status
+}
+
+// Return the error that caused the supervisor to give up, if any.
+func (supervisor *Supervisor) Err() error {
+
+	defer supervisor.lock.Unlock()
+	supervisor.lock.Lock()
+
+	return supervisor.err
+}
+
+// Body of the goroutine started by StartSupervisor.
+func (supervisor *Supervisor) run(
+
+	options SupervisorOptions,
+	specs []ChildSpec,
+	terminate <-chan any,
+	await chan<- any,
+
+) {
+
+	defer close(await)
+
+	var (
+		exits      = make(chan childExit)
+		restarts   = make(chan int)
+		done       = make(chan any)
+		children   = make([]*supervisedChild, len(specs))
+		restartLog = []time.Time{}
+		running    = 0
+		pending    = 0
+		delay      time.Duration
+	)
+
+	defer close(done)
+
+	setStatus := func(i int, update func(*ChildStatus)) {
+		defer supervisor.lock.Unlock()
+		supervisor.lock.Lock()
+		update(&supervisor.children[i])
+		children[i].state = supervisor.children[i].State
+	}
+
+	// return true if any child is waiting to be restarted
+	restarting := func() bool {
+		for _, c := range children {
+			if c.state == ChildRestarting {
+				return true
+			}
+		}
+		return false
+	}
+
+	start := func(i int) {
+		c := children[i]
+		ctx, cancel := context.WithCancel(context.Background())
+		c.cancel = cancel
+		c.running = true
+		c.started = time.Now()
+		running++
+		setStatus(i, func(status *ChildStatus) {
+			status.State = ChildRunning
+			status.Since = c.started
+		})
+		go func() {
+			var err error
+			if e := catchPanic(c.spec.Name, func() { err = c.spec.Start(ctx) }); e != nil {
+				err = e
+			}
+			exits <- childExit{child: i, err: err}
+		}()
+	}
+
+	// schedule a restart of the given child, or of all restartable children
+	// if i is negative, after the given delay
+	schedule := func(i int, delay time.Duration) {
+		time.AfterFunc(delay, func() {
+			select {
+			case restarts <- i:
+			case <-done:
+			}
+		})
+	}
+
+	// return the delay before restarting the given child, doubling
+	// MinBackoff for each consecutive failure without overflowing
+	backoff := func(c *supervisedChild) time.Duration {
+		if reset := cmp.Or(options.Period, options.MaxBackoff); reset > 0 && time.Since(c.started) >= reset {
+			c.failures = 0
+		}
+		c.failures++
+		limit := time.Duration(math.MaxInt64)
+		if options.MaxBackoff > 0 {
+			limit = options.MaxBackoff
+		}
+		delay := max(options.MinBackoff, 0)
+		for n := 1; n < c.failures && delay > 0 && delay < limit; n++ {
+			if delay > limit/2 {
+				delay = limit
+			} else {
+				delay *= 2
+			}
+		}
+		return min(delay, limit)
+	}
+
+	// cancel all running children and wait for them to exit
+	stopAll := func() {
+		for _, c := range children {
+			if c.running {
+				c.cancel()
+			}
+		}
+		for running > 0 {
+			exit := <-exits
+			children[exit.child].running = false
+			running--
+		}
+		now := time.Now()
+		for i, c := range children {
+			if c.state != ChildStopped {
+				setStatus(i, func(status *ChildStatus) {
+					status.State = ChildStopped
+					status.Since = now
+				})
+			}
+		}
+	}
+
+	for i, spec := range specs {
+		children[i] = &supervisedChild{spec: spec}
+		start(i)
+	}
+
+	for {
+
+		select {
+
+		case <-terminate:
+			stopAll()
+			return
+
+		case i := <-restarts:
+			if i >= 0 {
+				start(i)
+				continue
+			}
+			for j, c := range children {
+				if !c.running && c.state == ChildRestarting {
+					start(j)
+				}
+			}
+
+		case exit := <-exits:
+			c := children[exit.child]
+			c.running = false
+			c.cancel()
+			running--
+
+			if c.stopping {
+				// stopped in order to restart all children together, so its
+				// own exit does not decide whether it is restarted
+				c.stopping = false
+				if c.state == ChildRestarting {
+					setStatus(exit.child, func(status *ChildStatus) {
+						status.Restarts++
+					})
+				}
+				if pending--; pending == 0 {
+					schedule(-1, delay)
+				}
+				continue
+			}
+
+			restart := c.spec.Restart == Permanent || (c.spec.Restart == Transient && exit.err != nil)
+
+			setStatus(exit.child, func(status *ChildStatus) {
+				status.LastError = exit.err
+				status.Since = time.Now()
+				if restart {
+					status.State = ChildRestarting
+				} else {
+					status.State = ChildStopped
+				}
+			})
+
+			if !restart {
+				if running == 0 && pending == 0 && !restarting() {
+					return
+				}
+				continue
+			}
+
+			now := time.Now()
+
+			if options.MaxRestarts > 0 && options.Period > 0 {
+				for len(restartLog) > 0 && now.Sub(restartLog[0]) > options.Period {
+					restartLog = restartLog[1:]
+				}
+				if len(restartLog) >= options.MaxRestarts {
+					supervisor.lock.Lock()
+					supervisor.err = fmt.Errorf(
+						"%s exceeded %d restarts in %s: %w",
+						c.spec.Name,
+						options.MaxRestarts,
+						options.Period,
+						exit.err,
+					)
+					supervisor.lock.Unlock()
+					stopAll()
+					return
+				}
+			}
+
+			restartLog = append(restartLog, now)
+
+			setStatus(exit.child, func(status *ChildStatus) {
+				status.Restarts++
+			})
+
+			delay = backoff(c)
+
+			if options.Strategy != OneForAll {
+				schedule(exit.child, delay)
+				continue
+			}
+
+			for j, other := range children {
+				if other.state == ChildStopped || other.stopping {
+					continue
+				}
+				setStatus(j, func(status *ChildStatus) {
+					if other.spec.Restart == Temporary {
+						status.State = ChildStopped
+						status.Since = time.Now()
+					} else {
+						status.State = ChildRestarting
+					}
+				})
+				if other.running {
+					other.stopping = true
+					other.cancel()
+					pending++
+				}
+			}
+
+			if pending == 0 {
+				schedule(-1, delay)
+			}
+		}
+	}
+}
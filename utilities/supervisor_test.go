@@ -0,0 +1,193 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"parasaurolophus/utilities"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Return a ChildSpec.Start function which fails the given number of times
+// before running until its context is done, counting the number of times it
+// is started.
+func flakyChild(failures int, starts *atomic.Int32) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if n := starts.Add(1); int(n) <= failures {
+			return fmt.Errorf("failure %d", n)
+		}
+		<-ctx.Done()
+		return nil
+	}
+}
+
+// Wait for the given condition to become true, failing the test if it does not
+// do so within a second.
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSupervisorOneForOne(t *testing.T) {
+	var flakyStarts, steadyStarts atomic.Int32
+	children := []utilities.ChildSpec{
+		{Name: "flaky", Start: flakyChild(2, &flakyStarts)},
+		{Name: "steady", Start: flakyChild(0, &steadyStarts)},
+	}
+	options := utilities.SupervisorOptions{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond * 4}
+	supervisor, terminate, await := utilities.StartSupervisor(options, children)
+	eventually(t, func() bool {
+		return flakyStarts.Load() == 3
+	})
+	status := supervisor.Status()
+	if status[0].Restarts != 2 || status[0].State != utilities.ChildRunning {
+		t.Errorf("expected flaky to be running after 2 restarts, got %+v", status[0])
+	}
+	if status[0].LastError == nil || status[0].LastError.Error() != "failure 2" {
+		t.Errorf(`expected last error to be "failure 2", got %v`, status[0].LastError)
+	}
+	if status[1].Restarts != 0 || steadyStarts.Load() != 1 {
+		t.Errorf("expected steady not to be restarted, got %+v", status[1])
+	}
+	utilities.CloseAndWait(terminate, await)
+	for _, s := range supervisor.Status() {
+		if s.State != utilities.ChildStopped {
+			t.Errorf("expected %s to be stopped, got %s", s.Name, s.State)
+		}
+	}
+	if err := supervisor.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSupervisorOneForAll(t *testing.T) {
+	var flakyStarts, steadyStarts atomic.Int32
+	children := []utilities.ChildSpec{
+		{Name: "flaky", Start: flakyChild(1, &flakyStarts)},
+		{Name: "steady", Start: flakyChild(0, &steadyStarts)},
+	}
+	options := utilities.SupervisorOptions{Strategy: utilities.OneForAll}
+	supervisor, terminate, await := utilities.StartSupervisor(options, children)
+	defer utilities.CloseAndWait(terminate, await)
+	eventually(t, func() bool {
+		return flakyStarts.Load() == 2 && steadyStarts.Load() == 2
+	})
+	eventually(t, func() bool {
+		status := supervisor.Status()
+		return status[0].State == utilities.ChildRunning && status[1].State == utilities.ChildRunning
+	})
+	for _, s := range supervisor.Status() {
+		if s.Restarts != 1 {
+			t.Errorf("expected %s to be restarted once, got %d", s.Name, s.Restarts)
+		}
+	}
+}
+
+func TestSupervisorRestartIntensity(t *testing.T) {
+	var starts atomic.Int32
+	children := []utilities.ChildSpec{
+		{Name: "broken", Start: flakyChild(1000, &starts)},
+	}
+	options := utilities.SupervisorOptions{MaxRestarts: 3, Period: time.Minute}
+	supervisor, _, await := utilities.StartSupervisor(options, children)
+	select {
+	case <-await:
+	case <-time.After(time.Second):
+		t.Fatal("expected supervisor to give up")
+	}
+	if starts.Load() != 4 {
+		t.Errorf("expected 4 starts, got %d", starts.Load())
+	}
+	if err := supervisor.Err(); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSupervisorRestartPolicies(t *testing.T) {
+	var transientStarts, temporaryStarts atomic.Int32
+	children := []utilities.ChildSpec{
+		{
+			Name:    "transient",
+			Restart: utilities.Transient,
+			Start: func(context.Context) error {
+				if transientStarts.Add(1) == 1 {
+					panic(errors.New("boom"))
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "temporary",
+			Restart: utilities.Temporary,
+			Start: func(context.Context) error {
+				temporaryStarts.Add(1)
+				return errors.New("oops")
+			},
+		},
+	}
+	supervisor, _, await := utilities.StartSupervisor(utilities.SupervisorOptions{}, children)
+	select {
+	case <-await:
+	case <-time.After(time.Second):
+		t.Fatal("expected supervisor to exit once all children stopped")
+	}
+	if transientStarts.Load() != 2 || temporaryStarts.Load() != 1 {
+		t.Errorf("expected 2 and 1 starts, got %d and %d", transientStarts.Load(), temporaryStarts.Load())
+	}
+	status := supervisor.Status()
+	if status[0].Restarts != 1 || status[0].LastError != nil {
+		t.Errorf("expected transient to stop cleanly after 1 restart, got %+v", status[0])
+	}
+	if status[1].State != utilities.ChildStopped || status[1].LastError.Error() != "oops" {
+		t.Errorf("expected temporary to be stopped, got %+v", status[1])
+	}
+}
+
+func TestSupervisorOneForAllPolicies(t *testing.T) {
+	var flakyStarts, temporaryStarts, cleanStarts, errorStarts atomic.Int32
+	children := []utilities.ChildSpec{
+		{Name: "flaky", Start: flakyChild(1, &flakyStarts)},
+		{Name: "temporary", Start: flakyChild(0, &temporaryStarts), Restart: utilities.Temporary},
+		{Name: "clean", Start: flakyChild(0, &cleanStarts), Restart: utilities.Transient},
+		{
+			Name:    "error",
+			Restart: utilities.Transient,
+			Start: func(ctx context.Context) error {
+				errorStarts.Add(1)
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+	}
+	options := utilities.SupervisorOptions{Strategy: utilities.OneForAll}
+	supervisor, terminate, await := utilities.StartSupervisor(options, children)
+	defer utilities.CloseAndWait(terminate, await)
+	eventually(t, func() bool {
+		status := supervisor.Status()
+		return flakyStarts.Load() == 2 && cleanStarts.Load() == 2 && errorStarts.Load() == 2 &&
+			status[0].State == utilities.ChildRunning && status[2].State == utilities.ChildRunning &&
+			status[3].State == utilities.ChildRunning
+	})
+	if temporaryStarts.Load() != 1 {
+		t.Errorf("expected temporary to start once, got %d", temporaryStarts.Load())
+	}
+	status := supervisor.Status()
+	if status[1].State != utilities.ChildStopped || status[1].Restarts != 0 {
+		t.Errorf("expected temporary to be stopped without restarting, got %+v", status[1])
+	}
+	for _, i := range []int{0, 2, 3} {
+		if status[i].Restarts != 1 {
+			t.Errorf("expected %s to be restarted once, got %d", status[i].Name, status[i].Restarts)
+		}
+	}
+}
@@ -16,7 +16,9 @@ import (
 // request handler function or any similar "one and done" flow. But Go provides
 // no mechanism for forcibly terminating a goroutine, so long-running processes
 // should not use this function (or any that involve goroutines that could
-// possibly hang).
+// possibly hang). Consider using WithTimeLimitContext instead.
+//
+// See WithTimeLimitContext
 func WithTimeLimit[V any](
 
 	fn func() V,
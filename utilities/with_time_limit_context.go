@@ -0,0 +1,121 @@
+// Copyright 2024 Kirk Rader
+
+package utilities
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type (
+
+	// Error returned by WithTimeLimitContext when fn does not complete within
+	// the time limit. Abandoned is true if fn had still not returned by the
+	// end of the grace period, i.e. it did not honor the cancellation of its
+	// context.
+	//
+	// See WithTimeLimitContext
+	TimeoutError struct {
+		TimeLimit time.Duration
+		Abandoned bool
+	}
+)
+
+func (err *TimeoutError) Error() string {
+
+	if err.Abandoned {
+		return fmt.Sprintf("timed out after %s and abandoned", err.TimeLimit)
+	}
+
+	return fmt.Sprintf("timed out after %s", err.TimeLimit)
+}
+
+// Allow errors.Is(err, context.DeadlineExceeded) to match a TimeoutError.
+func (err *TimeoutError) Unwrap() error {
+
+	return context.DeadlineExceeded
+}
+
+// Like WithTimeLimit, but fn is passed a context which is canceled when the
+// time limit expires, and a timeout is reported by returning a *TimeoutError
+// rather than by calling a timeout function. Once the time limit has expired,
+// wait up to gracePeriod for fn to return before giving up on it.
+//
+// Unlike WithTimeLimit, this function is suitable for long-running processes
+// so long as fn honors the cancellation of its context, e.g. by passing it to
+// http.NewRequestWithContext, since the goroutine used to invoke fn then exits
+// promptly after a timeout. Even if fn does not do so, that goroutine will
+// exit whenever fn eventually returns. For example:
+//
+//	response, err := WithTimeLimitContext(
+//	  ctx,
+//	  func(ctx context.Context) (hue.Response, error) {
+//	    return bridge.SendContext(ctx, http.MethodGet, "resource", nil)
+//	  },
+//	  5*time.Second,
+//	  time.Second,
+//	)
+//
+// If ctx is done before the time limit expires, ctx.Err() is returned instead
+// of a *TimeoutError.
+//
+// See TimeoutError, WithTimeLimit
+func WithTimeLimitContext[V any](
+
+	ctx context.Context,
+	fn func(context.Context) (V, error),
+	timeLimit time.Duration,
+	gracePeriod time.Duration,
+
+) (
+
+	value V,
+	err error,
+
+) {
+
+	type result struct {
+		value V
+		err   error
+	}
+
+	limited, cancel := context.WithTimeout(ctx, timeLimit)
+	defer cancel()
+
+	// buffered so that the goroutine can exit even if no one is waiting for
+	// its result any longer
+	results := make(chan result, 1)
+
+	go func() {
+		var r result
+		r.value, r.err = fn(limited)
+		results <- r
+	}()
+
+	select {
+
+	case r := <-results:
+		if limited.Err() == nil {
+			return r.value, r.err
+		}
+
+	case <-limited.Done():
+		timer := time.NewTimer(gracePeriod)
+		defer timer.Stop()
+		select {
+		case <-results:
+		case <-timer.C:
+			if ctx.Err() == nil {
+				err = &TimeoutError{TimeLimit: timeLimit, Abandoned: true}
+				return
+			}
+		}
+	}
+
+	if err = ctx.Err(); err == nil {
+		err = &TimeoutError{TimeLimit: timeLimit}
+	}
+
+	return
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 Kirk Rader
+
+package utilities_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"parasaurolophus/utilities"
+	"testing"
+	"time"
+)
+
+func TestWithTimeLimitContext(t *testing.T) {
+	fn := func(ctx context.Context) (int, error) {
+		return 1, nil
+	}
+	v, err := utilities.WithTimeLimitContext(context.Background(), fn, time.Millisecond*50, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 1 {
+		t.Errorf("expected 1, got %d", v)
+	}
+	fn = func(ctx context.Context) (int, error) {
+		return 0, fmt.Errorf("oops")
+	}
+	if _, err := utilities.WithTimeLimitContext(context.Background(), fn, time.Millisecond*50, 0); err == nil || err.Error() != "oops" {
+		t.Errorf(`expected "oops", got %v`, err)
+	}
+}
+
+func TestWithTimeLimitContextTimeout(t *testing.T) {
+	canceled := make(chan any)
+	fn := func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		close(canceled)
+		return 2, ctx.Err()
+	}
+	v, err := utilities.WithTimeLimitContext(context.Background(), fn, time.Millisecond*10, time.Millisecond*50)
+	var timeoutError *utilities.TimeoutError
+	if !errors.As(err, &timeoutError) {
+		t.Fatalf("expected a TimeoutError, got %v", err)
+	}
+	if timeoutError.Abandoned {
+		t.Error("expected fn not to be abandoned")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v to match %v", err, context.DeadlineExceeded)
+	}
+	if v != 0 {
+		t.Errorf("expected 0, got %d", v)
+	}
+	select {
+	case <-canceled:
+	default:
+		t.Error("expected fn's context to be canceled")
+	}
+}
+
+func TestWithTimeLimitContextAbandoned(t *testing.T) {
+	release := make(chan any)
+	defer close(release)
+	fn := func(context.Context) (int, error) {
+		<-release
+		return 3, nil
+	}
+	_, err := utilities.WithTimeLimitContext(context.Background(), fn, time.Millisecond*10, time.Millisecond*10)
+	var timeoutError *utilities.TimeoutError
+	if !errors.As(err, &timeoutError) || !timeoutError.Abandoned {
+		t.Errorf("expected an abandoned TimeoutError, got %v", err)
+	}
+}
+
+func TestWithTimeLimitContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fn := func(ctx context.Context) (int, error) {
+		cancel()
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	_, err := utilities.WithTimeLimitContext(ctx, fn, time.Hour, 0)
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}